@@ -0,0 +1,219 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+const (
+	// v2SpecAnnotation keeps the v2 spec on a WebGame read as v1 when it holds fields v1 cannot represent,
+	// so an update through v1 does not lose them.
+	v2SpecAnnotation = "webgame.webgame.tech/v2-spec"
+	// v2StatusAnnotation kept the v2 status in earlier versions, it is dropped as the controller
+	// regenerates the status.
+	v2StatusAnnotation = "webgame.webgame.tech/v2-status"
+	// serverPortAnnotation keeps a named v1 serverPort, which v2 cannot represent.
+	serverPortAnnotation = v2.V1ServerPortAnnotation
+)
+
+var _ conversion.Convertible = &WebGame{}
+
+// ConvertTo converts this WebGame to the Hub version (v2).
+func (src *WebGame) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v2.WebGame)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	if err := popAnnotation(&dst.ObjectMeta.Annotations, v2SpecAnnotation, &dst.Spec); err != nil {
+		return err
+	}
+	removeAnnotation(&dst.ObjectMeta.Annotations, v2StatusAnnotation)
+
+	src.Spec.convertTo(&dst.Spec)
+	src.Status.convertTo(&dst.Status)
+
+	if src.Spec.ServerPort.Type == intstr.String {
+		setAnnotation(&dst.ObjectMeta.Annotations, serverPortAnnotation, src.Spec.ServerPort.StrVal)
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v2) to this version.
+func (dst *WebGame) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v2.WebGame)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec.convertFrom(&src.Spec)
+	dst.Status.convertFrom(&src.Status)
+
+	// restore a named serverPort kept by ConvertTo
+	if port, ok := dst.GetAnnotations()[serverPortAnnotation]; ok {
+		removeAnnotation(&dst.ObjectMeta.Annotations, serverPortAnnotation)
+		if src.Spec.Networking.ServerPort == 0 {
+			dst.Spec.ServerPort = intstr.FromString(port)
+		}
+	}
+
+	// keep what v1 cannot represent, the status is not kept since the controller regenerates it
+	var spec v2.WebGameSpec
+	dst.Spec.convertTo(&spec)
+	setV2Defaults(&spec, &src.Spec)
+	if !equality.Semantic.DeepEqual(spec, src.Spec) {
+		if err := pushAnnotation(&dst.ObjectMeta.Annotations, v2SpecAnnotation, src.Spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setV2Defaults sets the fields v1 cannot represent which the API server always defaults in v2 from src,
+// when src holds their default, so a spec which only differs from v1 by them needs no annotation.
+// The defaults of the optional fields only apply once a user sets them, which v1 cannot represent.
+func setV2Defaults(spec, src *v2.WebGameSpec) {
+	if src.Routing.Mode == v2.RoutingModePath {
+		spec.Routing.Mode = src.Routing.Mode
+	}
+	if src.DriftPolicy == v2.DriftPolicyCorrect {
+		spec.DriftPolicy = src.DriftPolicy
+	}
+	if limit := src.Rollout.RevisionHistoryLimit; limit != nil && *limit == v2.DefaultRevisionHistoryLimit {
+		spec.Rollout.RevisionHistoryLimit = limit
+	}
+}
+
+// convertTo sets the v2 fields known to v1, other fields of dst are left untouched.
+func (s *WebGameSpec) convertTo(dst *v2.WebGameSpec) {
+	dst.DisplayName = s.DisplayName
+	dst.GameType = s.GameType
+	dst.Container.Image = s.Image
+	dst.Container.ImagePullSecrets = s.ImagePullSecrets
+	dst.Networking.ServerPort = 0
+	if s.ServerPort.Type == intstr.Int {
+		dst.Networking.ServerPort = s.ServerPort.IntVal
+	}
+	dst.Networking.IngressClass = s.IngressClass
+	dst.Routing.Domain = s.Domain
+	dst.Routing.IndexPage = s.IndexPage
+	dst.Scaling.Replicas = s.Replicas
+	dst.DeletionPolicy = v2.DeletionPolicy(s.DeletionPolicy)
+	dst.Archive = nil
+	if s.Archive != nil {
+		dst.Archive = &v2.ArchiveSpec{
+			Image:        s.Archive.Image,
+			Command:      s.Archive.Command,
+			Args:         s.Archive.Args,
+			Destination:  s.Archive.Destination,
+			ClaimName:    s.Archive.ClaimName,
+			Env:          s.Archive.Env,
+			BackoffLimit: s.Archive.BackoffLimit,
+		}
+	}
+}
+
+func (s *WebGameSpec) convertFrom(src *v2.WebGameSpec) {
+	s.DisplayName = src.DisplayName
+	s.GameType = src.GameType
+	s.Image = src.Container.Image
+	s.ImagePullSecrets = src.Container.ImagePullSecrets
+	s.ServerPort = intstr.FromInt(int(src.Networking.ServerPort))
+	s.IngressClass = src.Networking.IngressClass
+	s.Domain = src.Routing.Domain
+	s.IndexPage = src.Routing.IndexPage
+	s.Replicas = src.Scaling.Replicas
+	s.DeletionPolicy = DeletionPolicy(src.DeletionPolicy)
+	s.Archive = nil
+	if src.Archive != nil {
+		s.Archive = &ArchiveSpec{
+			Image:        src.Archive.Image,
+			Command:      src.Archive.Command,
+			Args:         src.Archive.Args,
+			Destination:  src.Archive.Destination,
+			ClaimName:    src.Archive.ClaimName,
+			Env:          src.Archive.Env,
+			BackoffLimit: src.Archive.BackoffLimit,
+		}
+	}
+}
+
+// convertTo sets the v2 fields known to v1, other fields of dst are left untouched.
+func (s *WebGameStatus) convertTo(dst *v2.WebGameStatus) {
+	dst.DeploymentStatus = s.DeploymentStatus
+	dst.GameAddress = s.GameAddress
+	dst.ClusterIP = s.ClusterIP
+	dst.ObservedGeneration = s.ObservedGeneration
+	dst.Phase = v2.WebGamePhase(s.Phase)
+	dst.LastError = s.LastError
+	dst.Conditions = s.Conditions
+	dst.Archive = nil
+	if s.Archive != nil {
+		dst.Archive = &v2.ArchiveStatus{
+			Phase:          v2.ArchivePhase(s.Archive.Phase),
+			JobName:        s.Archive.JobName,
+			StartTime:      s.Archive.StartTime,
+			CompletionTime: s.Archive.CompletionTime,
+			Message:        s.Archive.Message,
+		}
+	}
+}
+
+func (s *WebGameStatus) convertFrom(src *v2.WebGameStatus) {
+	s.DeploymentStatus = src.DeploymentStatus
+	s.GameAddress = src.GameAddress
+	s.ClusterIP = src.ClusterIP
+	s.ObservedGeneration = src.ObservedGeneration
+	s.Phase = WebGamePhase(src.Phase)
+	s.LastError = src.LastError
+	s.Conditions = src.Conditions
+	s.Archive = nil
+	if src.Archive != nil {
+		s.Archive = &ArchiveStatus{
+			Phase:          ArchivePhase(src.Archive.Phase),
+			JobName:        src.Archive.JobName,
+			StartTime:      src.Archive.StartTime,
+			CompletionTime: src.Archive.CompletionTime,
+			Message:        src.Archive.Message,
+		}
+	}
+}
+
+func setAnnotation(annotations *map[string]string, key, value string) {
+	if *annotations == nil {
+		*annotations = map[string]string{}
+	}
+	(*annotations)[key] = value
+}
+
+// pushAnnotation stores value as JSON in the annotation key.
+func pushAnnotation(annotations *map[string]string, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s: %w", key, err)
+	}
+	setAnnotation(annotations, key, string(data))
+	return nil
+}
+
+func removeAnnotation(annotations *map[string]string, key string) {
+	delete(*annotations, key)
+	if len(*annotations) == 0 {
+		*annotations = nil
+	}
+}
+
+// popAnnotation removes the annotation key and unmarshals its JSON value into value.
+func popAnnotation(annotations *map[string]string, key string, value interface{}) error {
+	data, ok := (*annotations)[key]
+	if !ok {
+		return nil
+	}
+	removeAnnotation(annotations, key)
+	if err := json.Unmarshal([]byte(data), value); err != nil {
+		return fmt.Errorf("unable to unmarshal %s: %w", key, err)
+	}
+	return nil
+}
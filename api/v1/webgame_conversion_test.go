@@ -0,0 +1,209 @@
+package v1
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+func newV1WebGame() *WebGame {
+	replicas := int32(2)
+	webgame := &WebGame{}
+	webgame.SetNamespace("webgames")
+	webgame.SetName("webgame-2048")
+	webgame.Spec = WebGameSpec{
+		DisplayName:      "2048",
+		GameType:         "2048",
+		Domain:           "games.example.com",
+		IndexPage:        "/index.html",
+		IngressClass:     "nginx",
+		ServerPort:       intstr.FromInt(80),
+		Replicas:         &replicas,
+		Image:            "webgamedevelop/2048:latest",
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+	}
+	return webgame
+}
+
+func TestConvertV1RoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(webgame *WebGame)
+	}{{
+		name:   "plain spec",
+		mutate: func(*WebGame) {},
+	}, {
+		name: "named server port",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.ServerPort = intstr.FromString("http")
+		},
+	}, {
+		name: "archive deletion policy",
+		mutate: func(webgame *WebGame) {
+			backoffLimit := int32(3)
+			webgame.Spec.DeletionPolicy = DeletionPolicyArchive
+			webgame.Spec.Archive = &ArchiveSpec{Destination: "s3://archives/2048", ClaimName: "data", BackoffLimit: &backoffLimit}
+		},
+	}, {
+		name: "user annotations",
+		mutate: func(webgame *WebGame) {
+			webgame.SetAnnotations(map[string]string{"owner": "team-a"})
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newV1WebGame()
+			tt.mutate(src)
+
+			var hub v2.WebGame
+			if err := src.DeepCopy().ConvertTo(&hub); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			var dst WebGame
+			if err := dst.ConvertFrom(&hub); err != nil {
+				t.Fatalf("ConvertFrom: %v", err)
+			}
+			if !equality.Semantic.DeepEqual(src, &dst) {
+				t.Errorf("round trip changed the WebGame\nwant %+v\n got %+v", src, &dst)
+			}
+		})
+	}
+}
+
+func TestConvertV1NamedServerPort(t *testing.T) {
+	src := newV1WebGame()
+	src.Spec.ServerPort = intstr.FromString("http")
+
+	var hub v2.WebGame
+	if err := src.ConvertTo(&hub); err != nil {
+		t.Fatalf("ConvertTo: %v", err)
+	}
+	// v2 has no named port, the name is kept in an annotation until the port is set in v2
+	if hub.Spec.Networking.ServerPort != 0 {
+		t.Errorf("serverPort = %d, want 0", hub.Spec.Networking.ServerPort)
+	}
+	if got := hub.GetAnnotations()[serverPortAnnotation]; got != "http" {
+		t.Errorf("annotation %s = %q, want http", serverPortAnnotation, got)
+	}
+
+	hub.Spec.Networking.ServerPort = 8080
+	var dst WebGame
+	if err := dst.ConvertFrom(&hub); err != nil {
+		t.Fatalf("ConvertFrom: %v", err)
+	}
+	if dst.Spec.ServerPort != intstr.FromInt(8080) {
+		t.Errorf("serverPort = %v, want 8080", dst.Spec.ServerPort)
+	}
+	if _, ok := dst.GetAnnotations()[serverPortAnnotation]; ok {
+		t.Errorf("annotation %s is kept", serverPortAnnotation)
+	}
+}
+
+func TestConvertV2RoundTrip(t *testing.T) {
+	revisionHistoryLimit := int32(v2.DefaultRevisionHistoryLimit)
+	tests := []struct {
+		name string
+		// mutate sets the v2 fields of the test
+		mutate func(spec *v2.WebGameSpec)
+		// annotated is true when the v1 WebGame keeps the v2 spec in an annotation
+		annotated bool
+		// defaulted clears the fields lost by the round trip, which the API server defaults again
+		defaulted func(spec *v2.WebGameSpec)
+	}{{
+		name: "v2 defaults only",
+		mutate: func(spec *v2.WebGameSpec) {
+			spec.Routing.Mode = v2.RoutingModePath
+			spec.DriftPolicy = v2.DriftPolicyCorrect
+			spec.Rollout.RevisionHistoryLimit = &revisionHistoryLimit
+		},
+		defaulted: func(spec *v2.WebGameSpec) {
+			spec.Routing.Mode = ""
+			spec.DriftPolicy = ""
+			spec.Rollout.RevisionHistoryLimit = nil
+		},
+	}, {
+		name: "tls",
+		mutate: func(spec *v2.WebGameSpec) {
+			spec.Routing.TLS = &v2.TLSSpec{Mode: v2.TLSModeSecret, SecretName: "games-tls"}
+		},
+		annotated: true,
+	}, {
+		name: "host routing and size",
+		mutate: func(spec *v2.WebGameSpec) {
+			spec.Routing.Mode = v2.RoutingModeHost
+			spec.Container.Size = v2.SizeSmall
+		},
+		annotated: true,
+	}, {
+		name: "canary rollout",
+		mutate: func(spec *v2.WebGameSpec) {
+			spec.Rollout.Canary = &v2.CanaryStrategy{Steps: []v2.CanaryStep{{Weight: 20}}}
+		},
+		annotated: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var src v2.WebGame
+			if err := newV1WebGame().ConvertTo(&src); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			tt.mutate(&src.Spec)
+			src.Status.EffectiveSpec = src.Spec.DeepCopy()
+
+			var v1 WebGame
+			if err := v1.ConvertFrom(src.DeepCopy()); err != nil {
+				t.Fatalf("ConvertFrom: %v", err)
+			}
+			if _, ok := v1.GetAnnotations()[v2SpecAnnotation]; ok != tt.annotated {
+				t.Errorf("annotation %s set = %v, want %v", v2SpecAnnotation, ok, tt.annotated)
+			}
+			if _, ok := v1.GetAnnotations()[v2StatusAnnotation]; ok {
+				t.Errorf("annotation %s is set, the status is regenerated by the controller", v2StatusAnnotation)
+			}
+
+			var dst v2.WebGame
+			if err := v1.ConvertTo(&dst); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			want := src.Spec.DeepCopy()
+			if tt.defaulted != nil {
+				tt.defaulted(want)
+			}
+			if !equality.Semantic.DeepEqual(want, &dst.Spec) {
+				t.Errorf("round trip changed the spec\nwant %+v\n got %+v", want, &dst.Spec)
+			}
+			if len(dst.GetAnnotations()) != 0 {
+				t.Errorf("annotations = %v, want none", dst.GetAnnotations())
+			}
+		})
+	}
+}
+
+func TestConvertV1UpdateKeepsV2Fields(t *testing.T) {
+	var src v2.WebGame
+	if err := newV1WebGame().ConvertTo(&src); err != nil {
+		t.Fatalf("ConvertTo: %v", err)
+	}
+	src.Spec.Routing.TLS = &v2.TLSSpec{Mode: v2.TLSModeSecret, SecretName: "games-tls"}
+
+	var v1 WebGame
+	if err := v1.ConvertFrom(&src); err != nil {
+		t.Fatalf("ConvertFrom: %v", err)
+	}
+	// an update through v1 changes the v1 fields, the v2 fields come from the annotation
+	v1.Spec.Image = "webgamedevelop/2048:v2"
+	var dst v2.WebGame
+	if err := v1.ConvertTo(&dst); err != nil {
+		t.Fatalf("ConvertTo: %v", err)
+	}
+	if dst.Spec.Container.Image != "webgamedevelop/2048:v2" {
+		t.Errorf("image = %s, want webgamedevelop/2048:v2", dst.Spec.Container.Image)
+	}
+	if !equality.Semantic.DeepEqual(dst.Spec.Routing.TLS, src.Spec.Routing.TLS) {
+		t.Errorf("tls = %+v, want %+v", dst.Spec.Routing.TLS, src.Spec.Routing.TLS)
+	}
+}
@@ -21,13 +21,139 @@ type WebGameSpec struct {
 	Image        string             `json:"image"`
 	// +kubebuilder:validation:Optional
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// DeletionPolicy decides what happens to the Deployment, Service and Ingress when the WebGame is deleted.
+	// +kubebuilder:default:=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Archive configures the cleanup step run before the WebGame is released, used by the Archive deletion policy.
+	// +optional
+	Archive *ArchiveSpec `json:"archive,omitempty"`
 }
 
+// DeletionPolicy describes how child resources are handled when a WebGame is deleted
+// +kubebuilder:validation:Enum=Delete;Retain;Archive
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the child resources together with the WebGame.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps the child resources and removes their owner references.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyArchive runs the archive job before the child resources are deleted.
+	DeletionPolicyArchive DeletionPolicy = "Archive"
+)
+
+// ArchiveSpec describes the job that copies game data to Destination before the WebGame is released
+type ArchiveSpec struct {
+	// Image of the archive container, defaults to the game image.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Command of the archive container.
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args of the archive container.
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Destination is where the game data is copied to, exposed to the container as WEBGAME_ARCHIVE_DESTINATION.
+	Destination string `json:"destination"`
+	// ClaimName is the PersistentVolumeClaim holding the game data, mounted at /data.
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+	// Env is a list of additional environment variables of the archive container.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// BackoffLimit is the number of retries before the archive job is marked as failed.
+	// +kubebuilder:default:=3
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+}
+
+// ArchivePhase is the progress of the archive step
+// +kubebuilder:validation:Enum=Pending;Running;Succeeded;Failed
+type ArchivePhase string
+
+const (
+	// ArchivePending means the archive job has not started yet.
+	ArchivePending ArchivePhase = "Pending"
+	// ArchiveRunning means the archive job is running.
+	ArchiveRunning ArchivePhase = "Running"
+	// ArchiveSucceeded means the game data was archived and the WebGame can be released.
+	ArchiveSucceeded ArchivePhase = "Succeeded"
+	// ArchiveFailed means the archive job failed, the WebGame is kept until the policy is changed.
+	ArchiveFailed ArchivePhase = "Failed"
+)
+
+// ArchiveStatus reports how far the archive step has got
+type ArchiveStatus struct {
+	Phase ArchivePhase `json:"phase"`
+	// JobName is the name of the archive job.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Condition types reported in WebGameStatus.Conditions.
+const (
+	// ConditionReady is True when every child resource is ready and the game is serving.
+	ConditionReady = "Ready"
+	// ConditionProgressing is True while child resources are being created or rolled out.
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded is True when the game is unhealthy or the last reconcile failed.
+	ConditionDegraded = "Degraded"
+	// ConditionDeploymentReady reflects the availability of the game Deployment.
+	ConditionDeploymentReady = "DeploymentReady"
+	// ConditionServiceReady reflects the state of the game Service.
+	ConditionServiceReady = "ServiceReady"
+	// ConditionIngressReady reflects the state of the game Ingress.
+	ConditionIngressReady = "IngressReady"
+)
+
+// WebGamePhase is a one-word summary of the WebGame conditions
+// +kubebuilder:validation:Enum=Pending;Progressing;Ready;Degraded;Failed;Terminating
+type WebGamePhase string
+
+const (
+	// PhasePending means the WebGame has not been reconciled yet.
+	PhasePending WebGamePhase = "Pending"
+	// PhaseProgressing means child resources are being created or rolled out.
+	PhaseProgressing WebGamePhase = "Progressing"
+	// PhaseReady means the game is available at its address.
+	PhaseReady WebGamePhase = "Ready"
+	// PhaseDegraded means the game is running but unhealthy.
+	PhaseDegraded WebGamePhase = "Degraded"
+	// PhaseFailed means the last reconcile returned an error.
+	PhaseFailed WebGamePhase = "Failed"
+	// PhaseTerminating means the WebGame is being deleted.
+	PhaseTerminating WebGamePhase = "Terminating"
+)
+
 // WebGameStatus defines the observed state of WebGame
 type WebGameStatus struct {
 	DeploymentStatus appsv1.DeploymentStatus `json:"deploymentStatus,omitempty"`
 	GameAddress      string                  `json:"gameAddress,omitempty"`
 	ClusterIP        string                  `json:"clusterIP,omitempty"`
+	// ObservedGeneration is the most recent WebGame generation handled by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Phase summarizes the conditions below.
+	// +optional
+	Phase WebGamePhase `json:"phase,omitempty"`
+	// LastError is the error returned by the last failed reconcile, cleared on success.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// Conditions represent the latest available observations of the WebGame state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Archive reports the progress of the archive step while the WebGame is being deleted.
+	// +optional
+	Archive *ArchiveStatus `json:"archive,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -41,6 +167,9 @@ type WebGameStatus struct {
 // +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.deploymentStatus.readyReplicas"
 // +kubebuilder:printcolumn:name="Updated",type="integer",JSONPath=".status.deploymentStatus.updatedReplicas"
 // +kubebuilder:printcolumn:name="Observed",type="integer",JSONPath=".status.deploymentStatus.observedGeneration"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason",priority=1
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message",priority=1
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 
 // WebGame is the Schema for the webgames API
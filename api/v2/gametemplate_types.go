@@ -0,0 +1,74 @@
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GameTemplateSpec defines the defaults and the policy of the WebGames of a game type
+type GameTemplateSpec struct {
+	// Defaults are used for the fields the WebGames of the game type leave empty.
+	// +optional
+	Defaults GameDefaults `json:"defaults,omitempty"`
+	// AllowedImageRepositories restricts the images of the game type to these repositories,
+	// like docker.io/webgamedevelop. Any image is allowed when empty.
+	// +optional
+	AllowedImageRepositories []string `json:"allowedImageRepositories,omitempty"`
+	// Sizes are the resource presets of the game type, they override the presets of the controller configuration.
+	// +optional
+	Sizes map[Size]corev1.ResourceRequirements `json:"sizes,omitempty"`
+	// MaxInstances limits the number of WebGames of the game type in the cluster, the oldest ones are deployed.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxInstances *int32 `json:"maxInstances,omitempty"`
+	// MaxInstancesPerNamespace limits the number of WebGames of the game type in a namespace, the oldest ones are deployed.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxInstancesPerNamespace *int32 `json:"maxInstancesPerNamespace,omitempty"`
+}
+
+// GameDefaults are the defaults of the WebGames of a game type
+type GameDefaults struct {
+	// +optional
+	Image string `json:"image,omitempty"`
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// +optional
+	Size Size `json:"size,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	ServerPort int32 `json:"serverPort,omitempty"`
+	// +optional
+	IngressClass string `json:"ingressClass,omitempty"`
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	IndexPage string `json:"indexPage,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=gt
+// +kubebuilder:printcolumn:name="Image",type="string",JSONPath=".spec.defaults.image"
+// +kubebuilder:printcolumn:name="MaxInstances",type="integer",JSONPath=".spec.maxInstances"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// GameTemplate is the Schema for the gametemplates API. It is named after the game type it applies to.
+type GameTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GameTemplateSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GameTemplateList contains a list of GameTemplate
+type GameTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GameTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GameTemplate{}, &GameTemplateList{})
+}
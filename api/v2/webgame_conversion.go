@@ -0,0 +1,7 @@
+package v2
+
+// V1ServerPortAnnotation keeps the named serverPort of a WebGame written as v1, which v2 cannot represent.
+const V1ServerPortAnnotation = "webgame.webgame.tech/v1-server-port"
+
+// Hub marks this type as a conversion hub.
+func (*WebGame) Hub() {}
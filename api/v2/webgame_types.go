@@ -0,0 +1,789 @@
+package v2
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// WebGameSpec defines the desired state of WebGame
+type WebGameSpec struct {
+	DisplayName string `json:"displayName"`
+	GameType    string `json:"gameType"`
+	// Source selects where the game is served from, the game container image when unset.
+	// +optional
+	Source *SourceSpec `json:"source,omitempty"`
+	// Container describes the game container.
+	// +optional
+	Container ContainerSpec `json:"container,omitempty"`
+	// Networking describes how the game is exposed inside the cluster.
+	// +optional
+	Networking NetworkingSpec `json:"networking,omitempty"`
+	// Routing describes the external address of the game.
+	// +kubebuilder:default:={}
+	// +optional
+	Routing RoutingSpec `json:"routing,omitempty"`
+	// Scaling describes the number of game replicas.
+	// +optional
+	Scaling ScalingSpec `json:"scaling,omitempty"`
+	// Rollout describes how a new game image is rolled out.
+	// +optional
+	Rollout RolloutSpec `json:"rollout,omitempty"`
+	// RollbackTo is a revision of status.history to roll the game back to.
+	// The controller sets the container and the server port of the revision and clears the field.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RollbackTo *int64 `json:"rollbackTo,omitempty"`
+	// Disruption limits voluntary disruptions of the game pods, like node drains.
+	// A budget of maxUnavailable 1 is used when unset, no budget is created for a single replica.
+	// +optional
+	Disruption *DisruptionSpec `json:"disruption,omitempty"`
+	// Schedules change the replicas of the game at the times of their cron expressions, the game runs
+	// the replicas of the schedule which started last, or spec.scaling.replicas while none has started.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Schedules []ScheduleSpec `json:"schedules,omitempty"`
+	// Idle scales the game to zero after a period without HTTP requests. The route of the game goes through
+	// the activator of the controller, awake or idle, which measures its activity and wakes it on a request.
+	// +optional
+	Idle *IdleSpec `json:"idle,omitempty"`
+	// DriftPolicy decides what happens when another manager changes fields the controller applies to the
+	// Deployment, Service and Ingress of the game.
+	// +kubebuilder:default:=Correct
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+	// DeletionPolicy decides what happens to the Deployment, Service and Ingress when the WebGame is deleted.
+	// +kubebuilder:default:=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Archive configures the cleanup step run before the WebGame is released, used by the Archive deletion policy.
+	// +optional
+	Archive *ArchiveSpec `json:"archive,omitempty"`
+}
+
+// ContainerSpec describes the game container
+type ContainerSpec struct {
+	// Image of the game, from the GameTemplate of the game type when empty.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// Size selects a resource preset from the controller configuration.
+	// +optional
+	Size Size `json:"size,omitempty"`
+	// Resources of the game container, they override the requests and limits of the size preset.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Probes override the HTTP probes of the game container against the index page on the server port.
+	// +optional
+	Probes *ProbesSpec `json:"probes,omitempty"`
+}
+
+// SourceSpec describes where the game is served from
+type SourceSpec struct {
+	// Static serves an HTML5 bundle from a shared nginx Deployment instead of a game Deployment,
+	// spec.container is ignored.
+	// +optional
+	Static *StaticSource `json:"static,omitempty"`
+}
+
+// StaticSource is the bundle of a static game, only one of ConfigMap, Secret and ArchiveURL can be set
+type StaticSource struct {
+	// ConfigMap holds the files of the bundle, one key per file at the root of the game.
+	// +optional
+	ConfigMap *corev1.LocalObjectReference `json:"configMap,omitempty"`
+	// Secret holds the files of the bundle, one key per file at the root of the game.
+	// +optional
+	Secret *corev1.LocalObjectReference `json:"secret,omitempty"`
+	// ArchiveURL is a .zip or .tar.gz archive of the bundle, downloaded when the shared pods start.
+	// +optional
+	ArchiveURL string `json:"archiveURL,omitempty"`
+	// Pool selects the shared nginx Deployment serving the bundle, defaults to the pool of the controller configuration.
+	// +optional
+	Pool StaticPool `json:"pool,omitempty"`
+}
+
+// StaticPool is how static games share nginx Deployments
+// +kubebuilder:validation:Enum=Namespace;GameType
+type StaticPool string
+
+const (
+	// StaticPoolNamespace serves the static games of a namespace from one Deployment.
+	StaticPoolNamespace StaticPool = "Namespace"
+	// StaticPoolGameType serves the static games of a namespace and a game type from one Deployment.
+	StaticPoolGameType StaticPool = "GameType"
+)
+
+// ProbesSpec overrides the probes of the game container. A probe without a handler keeps
+// the default HTTP GET of the index page, so only its thresholds are overridden.
+type ProbesSpec struct {
+	// +optional
+	Readiness *corev1.Probe `json:"readiness,omitempty"`
+	// +optional
+	Liveness *corev1.Probe `json:"liveness,omitempty"`
+	// +optional
+	Startup *corev1.Probe `json:"startup,omitempty"`
+}
+
+// Size is the name of a resource preset
+// +kubebuilder:validation:Enum=small;medium;large
+type Size string
+
+const (
+	SizeSmall  Size = "small"
+	SizeMedium Size = "medium"
+	SizeLarge  Size = "large"
+)
+
+// NetworkingSpec describes the game Service and Ingress
+type NetworkingSpec struct {
+	// ServerPort is the port the game container listens on, also used as the Service port.
+	// Defaults to the port of the GameTemplate of the game type, else 80.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	ServerPort int32 `json:"serverPort,omitempty"`
+	// IngressClass of the game Ingress, defaulted from the GameTemplate of the game type, else from cluster policy.
+	// +optional
+	IngressClass string `json:"ingressClass,omitempty"`
+}
+
+// RoutingSpec describes the external address of the game
+type RoutingSpec struct {
+	// Mode selects whether the game is served under a path of the domain, or on its own host.
+	// +kubebuilder:default:=Path
+	// +optional
+	Mode RoutingMode `json:"mode,omitempty"`
+	// +kubebuilder:default:=localhost
+	// +optional
+	Domain string `json:"domain,omitempty"`
+	// IndexPage is the entry page of the game, relative to the game address.
+	// Defaults to the index page of the GameTemplate of the game type, else /.
+	// +optional
+	IndexPage string `json:"indexPage,omitempty"`
+	// TLS serves the game over HTTPS, the game is served over plain HTTP when unset.
+	// With the Gateway backend only the hostname is set on the HTTPRoute, the Gateway listener serves the certificate.
+	// +optional
+	TLS *TLSSpec `json:"tls,omitempty"`
+	// Backend selects how the game is routed, defaults to the backend of the controller configuration.
+	// +optional
+	Backend RoutingBackend `json:"backend,omitempty"`
+	// Gateway is the parent of the HTTPRoute of the Gateway backend, defaults to the gateway of the controller configuration.
+	// +optional
+	Gateway *GatewayReference `json:"gateway,omitempty"`
+}
+
+// RoutingMode is how the game address is built
+// +kubebuilder:validation:Enum=Path;Host
+type RoutingMode string
+
+const (
+	// RoutingModePath serves the game at <domain>/<gameType>/<name>/, the path is stripped before requests reach the game.
+	RoutingModePath RoutingMode = "Path"
+	// RoutingModeHost serves the game at the root of its own host, built from the host template of the
+	// controller configuration, <name>.<gameType>.<domain> by default.
+	RoutingModeHost RoutingMode = "Host"
+)
+
+// RoutingBackend is the kind of resource routing requests to the game
+// +kubebuilder:validation:Enum=Ingress;Gateway
+type RoutingBackend string
+
+const (
+	// RoutingBackendIngress routes the game with a networking.k8s.io Ingress.
+	RoutingBackendIngress RoutingBackend = "Ingress"
+	// RoutingBackendGateway routes the game with a Gateway API HTTPRoute.
+	RoutingBackendGateway RoutingBackend = "Gateway"
+)
+
+// GatewayReference refers to a Gateway API Gateway
+type GatewayReference struct {
+	Name string `json:"name"`
+	// Namespace of the gateway, defaults to the WebGame namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// SectionName is the listener of the gateway the route attaches to.
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// TLSMode selects where the certificate of the game address comes from
+// +kubebuilder:validation:Enum=Secret;Wildcard;CertManager
+type TLSMode string
+
+const (
+	// TLSModeSecret uses an existing secret of the WebGame namespace.
+	TLSModeSecret TLSMode = "Secret"
+	// TLSModeWildcard uses the shared wildcard certificate of the platform configuration.
+	TLSModeWildcard TLSMode = "Wildcard"
+	// TLSModeCertManager has cert-manager issue the certificate into the secret.
+	TLSModeCertManager TLSMode = "CertManager"
+)
+
+// TLSSpec describes the certificate and HTTPS behaviour of the game address
+type TLSSpec struct {
+	Mode TLSMode `json:"mode"`
+	// SecretName is the kubernetes.io/tls secret holding the certificate, required by the Secret mode.
+	// The CertManager mode writes the certificate to it, and defaults it to <name>-tls.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// Issuer is the cert-manager issuer of the certificate, required by the CertManager mode.
+	// +optional
+	Issuer *IssuerReference `json:"issuer,omitempty"`
+	// RedirectHTTP redirects plain HTTP requests to HTTPS.
+	// +kubebuilder:default:=true
+	// +optional
+	RedirectHTTP *bool `json:"redirectHTTP,omitempty"`
+	// HSTS sends the Strict-Transport-Security header when set. The nginx ingress dialect does not support it,
+	// ingress-nginx sets the header for every ingress from its ConfigMap.
+	// +optional
+	HSTS *HSTSSpec `json:"hsts,omitempty"`
+}
+
+// IssuerReference refers to a cert-manager Issuer or ClusterIssuer
+type IssuerReference struct {
+	Name string `json:"name"`
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default:=Issuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// HSTSSpec describes the Strict-Transport-Security header
+type HSTSSpec struct {
+	// MaxAge is how long, in seconds, browsers only use HTTPS for the domain.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default:=31536000
+	// +optional
+	MaxAge int64 `json:"maxAge,omitempty"`
+	// +optional
+	IncludeSubDomains bool `json:"includeSubDomains,omitempty"`
+	// +optional
+	Preload bool `json:"preload,omitempty"`
+}
+
+// ScalingSpec describes the number of game replicas
+type ScalingSpec struct {
+	// Replicas is the number of game pods, defaulted from cluster policy.
+	// It is ignored while Autoscaling is set.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Autoscaling scales the game pods with a HorizontalPodAutoscaler.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+}
+
+// AutoscalingSpec describes the HorizontalPodAutoscaler of the game
+type AutoscalingSpec struct {
+	// MinReplicas is the lower limit of game pods.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the upper limit of game pods.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetCPUUtilization is the average CPU utilization of the game pods, in percent of their requests.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+	// TargetMemoryUtilization is the average memory utilization of the game pods, in percent of their requests.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+	// Metrics are additional metrics, like custom or external metrics, the game is scaled on.
+	// +optional
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
+}
+
+// RolloutSpec describes how a new game image is rolled out
+type RolloutSpec struct {
+	// Canary rolls a new image out to a canary Deployment first, shifting traffic to it step by step.
+	// Image changes are plain rolling updates of the game Deployment when unset.
+	// +optional
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+	// AutoRollback rolls the game back to the last successful revision when a revision exceeds
+	// the progress deadline of the Deployment or its pods crash-loop.
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
+	// RevisionHistoryLimit is the number of revisions kept in status.history.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=10
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+}
+
+// DefaultRevisionHistoryLimit is the number of revisions kept in status.history when spec.rollout.revisionHistoryLimit is unset.
+const DefaultRevisionHistoryLimit = 10
+
+// CanaryStrategy describes the steps of a canary rollout
+type CanaryStrategy struct {
+	// Replicas is the number of canary pods.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Steps are the traffic weights given to the canary in order, the image is promoted after the last step.
+	// +kubebuilder:validation:MinItems=1
+	Steps []CanaryStep `json:"steps"`
+	// Analysis checks the canary over HTTP in addition to the readiness of its pods.
+	// +optional
+	Analysis *CanaryAnalysis `json:"analysis,omitempty"`
+	// Paused holds the rollout at its current step, the step starts over when it is unset.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// Abort sends all traffic back to the stable image and removes the canary.
+	// The rollout starts over when it is unset.
+	// +optional
+	Abort bool `json:"abort,omitempty"`
+}
+
+// CanaryStep is a traffic weight of the canary
+type CanaryStep struct {
+	// Weight is the percentage of requests sent to the canary.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+	// Pause is how long the step lasts once the canary is ready, the next step starts right away when unset.
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+// CanaryAnalysis describes the HTTP check of the canary
+type CanaryAnalysis struct {
+	// Path requested on the canary Service, the index page when unset. Any status below 400 is a success.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Interval between two checks.
+	// +kubebuilder:default:="30s"
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// FailureLimit is the number of failed checks aborting the rollout.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=3
+	// +optional
+	FailureLimit int32 `json:"failureLimit,omitempty"`
+}
+
+// DisruptionSpec describes the PodDisruptionBudget of the game, only one of the fields can be set
+type DisruptionSpec struct {
+	// MinAvailable is the number or percentage of game pods which must stay available.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number or percentage of game pods which can be unavailable.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// ScheduleSpec scales the game when its cron expression matches
+type ScheduleSpec struct {
+	// Name identifies the schedule in status.
+	Name string `json:"name"`
+	// Schedule is a cron expression in the standard five fields format, like "0 18 * * 5" for 18:00 on Fridays.
+	Schedule string `json:"schedule"`
+	// TimeZone of the schedule, a name of the IANA time zone database like Europe/Paris. UTC when unset.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Replicas of the game while the schedule is active. 0 stops the game, with autoscaling the
+	// replicas are the minimum of the autoscaler.
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas"`
+	// Duration ends the schedule after it started, the game then goes back to the schedule which started
+	// before or to spec.scaling.replicas. The schedule stays active until another one starts when unset.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// IdleSpec describes when an unused game is scaled to zero. The routes of the game always point to the activator
+// of the manager, awake or idle: the activator proxies every request to the game and records the last one, which
+// is how the activity of the game is measured. The activator is reached through an ExternalName Service, only
+// resolved by the Gateway implementations supporting them, others report HTTPRouteReady False with RefsNotResolved.
+type IdleSpec struct {
+	// Timeout is how long the game runs without HTTP requests before it is scaled to zero.
+	// +kubebuilder:default:="30m"
+	// +optional
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DriftPolicy describes how changes made by other managers to the fields the controller applies are handled
+// +kubebuilder:validation:Enum=Correct;ReportOnly
+type DriftPolicy string
+
+const (
+	// DriftPolicyCorrect takes the changed fields back.
+	DriftPolicyCorrect DriftPolicy = "Correct"
+	// DriftPolicyReportOnly reports the changed fields in status.drift and leaves the child resource as it is,
+	// the child resource is not updated until the drift is resolved or the policy is changed.
+	DriftPolicyReportOnly DriftPolicy = "ReportOnly"
+)
+
+// DeletionPolicy describes how child resources are handled when a WebGame is deleted
+// +kubebuilder:validation:Enum=Delete;Retain;Archive
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the child resources together with the WebGame.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps the child resources and removes their owner references.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyArchive runs the archive job before the child resources are deleted.
+	DeletionPolicyArchive DeletionPolicy = "Archive"
+)
+
+// ArchiveSpec describes the job that copies game data to Destination before the WebGame is released
+type ArchiveSpec struct {
+	// Image of the archive container, defaults to the game image.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Command of the archive container.
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args of the archive container.
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Destination is where the game data is copied to, exposed to the container as WEBGAME_ARCHIVE_DESTINATION.
+	Destination string `json:"destination"`
+	// ClaimName is the PersistentVolumeClaim holding the game data, mounted at /data.
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+	// Env is a list of additional environment variables of the archive container.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// BackoffLimit is the number of retries before the archive job is marked as failed.
+	// +kubebuilder:default:=3
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+}
+
+// Condition types reported in WebGameStatus.Conditions.
+const (
+	// ConditionReady is True when every child resource is ready and the game is serving.
+	ConditionReady = "Ready"
+	// ConditionProgressing is True while child resources are being created or rolled out.
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded is True when the game is unhealthy or the last reconcile failed.
+	ConditionDegraded = "Degraded"
+	// ConditionDeploymentReady reflects the availability of the game Deployment.
+	ConditionDeploymentReady = "DeploymentReady"
+	// ConditionServiceReady reflects the state of the game Service.
+	ConditionServiceReady = "ServiceReady"
+	// ConditionIngressReady reflects the state of the game Ingress.
+	ConditionIngressReady = "IngressReady"
+	// ConditionImagePullSecretsReady is False when an image pull secret is missing or has the wrong type.
+	ConditionImagePullSecretsReady = "ImagePullSecretsReady"
+	// ConditionHTTPRouteReady reflects the state of the game HTTPRoute, it replaces IngressReady with the Gateway backend.
+	ConditionHTTPRouteReady = "HTTPRouteReady"
+	// ConditionCertificateReady is False while the TLS secret does not hold a certificate, only set when TLS is enabled.
+	ConditionCertificateReady = "CertificateReady"
+	// ConditionContainersHealthy is False when the probes of the game containers are failing, only set while pods are running.
+	ConditionContainersHealthy = "ContainersHealthy"
+	// ConditionRolloutComplete is False while a canary rollout runs or after it was aborted, only set with the canary strategy.
+	ConditionRolloutComplete = "RolloutComplete"
+	// ConditionBundleReady is False when the ConfigMap or the Secret of the static bundle is missing, or when the
+	// shared pods failed to download the archive of the bundle, only set for static games.
+	ConditionBundleReady = "BundleReady"
+	// ConditionSpecResolved is False when the spec merged over the GameTemplate is incomplete or breaks the template policy.
+	ConditionSpecResolved = "SpecResolved"
+	// ConditionDrifted is True while other managers changed fields of the child resources which are not corrected,
+	// it does not count towards the Ready condition.
+	ConditionDrifted = "Drifted"
+)
+
+// WebGamePhase is a one-word summary of the WebGame conditions
+// +kubebuilder:validation:Enum=Pending;Progressing;Ready;Degraded;Failed;Terminating
+type WebGamePhase string
+
+const (
+	// PhasePending means the WebGame has not been reconciled yet.
+	PhasePending WebGamePhase = "Pending"
+	// PhaseProgressing means child resources are being created or rolled out.
+	PhaseProgressing WebGamePhase = "Progressing"
+	// PhaseReady means the game is available at its address.
+	PhaseReady WebGamePhase = "Ready"
+	// PhaseDegraded means the game is running but unhealthy.
+	PhaseDegraded WebGamePhase = "Degraded"
+	// PhaseFailed means the last reconcile returned an error.
+	PhaseFailed WebGamePhase = "Failed"
+	// PhaseTerminating means the WebGame is being deleted.
+	PhaseTerminating WebGamePhase = "Terminating"
+)
+
+// RolloutPhase is the progress of a canary rollout
+// +kubebuilder:validation:Enum=Progressing;Paused;Promoting;Succeeded;Aborted
+type RolloutPhase string
+
+const (
+	// RolloutProgressing means the canary is running and traffic is shifted to it step by step.
+	RolloutProgressing RolloutPhase = "Progressing"
+	// RolloutPaused means the rollout is held at its current step by spec.rollout.canary.paused.
+	RolloutPaused RolloutPhase = "Paused"
+	// RolloutPromoting means the steps are done and the game Deployment is updated to the canary image.
+	RolloutPromoting RolloutPhase = "Promoting"
+	// RolloutSucceeded means the canary image was promoted and the canary removed.
+	RolloutSucceeded RolloutPhase = "Succeeded"
+	// RolloutAborted means traffic went back to the stable image and the canary was removed.
+	RolloutAborted RolloutPhase = "Aborted"
+)
+
+// RolloutStatus reports how far the canary rollout has got
+type RolloutStatus struct {
+	Phase RolloutPhase `json:"phase"`
+	// StableImage is the image of the game Deployment while the canary runs.
+	StableImage string `json:"stableImage"`
+	// CanaryImage is the image being rolled out.
+	CanaryImage string `json:"canaryImage"`
+	// Step is the index of the current step.
+	// +optional
+	Step int32 `json:"step,omitempty"`
+	// Weight is the percentage of requests currently sent to the canary.
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
+	// StepStartTime is when the canary was ready at the current step.
+	// +optional
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+	// FailedChecks is the number of failed HTTP checks of the canary.
+	// +optional
+	FailedChecks int32 `json:"failedChecks,omitempty"`
+	// LastCheckTime is when the canary was last checked over HTTP.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// Reason is why the rollout was aborted.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RevisionOutcome is the result of applying a revision
+// +kubebuilder:validation:Enum=Progressing;Succeeded;Failed
+type RevisionOutcome string
+
+const (
+	// RevisionProgressing means the game Deployment is rolling the revision out.
+	RevisionProgressing RevisionOutcome = "Progressing"
+	// RevisionSucceeded means every replica of the revision became available.
+	RevisionSucceeded RevisionOutcome = "Succeeded"
+	// RevisionFailed means the revision exceeded the progress deadline or its pods crash-looped.
+	RevisionFailed RevisionOutcome = "Failed"
+)
+
+// Revision is a game container spec applied to the game Deployment
+type Revision struct {
+	// Revision is the number of the revision, increasing with every applied change.
+	Revision int64  `json:"revision"`
+	Image    string `json:"image"`
+	// SpecHash is the hash of the container spec and the server port of the revision.
+	SpecHash string `json:"specHash"`
+	// Container and ServerPort are the spec of the revision, restored by a rollback.
+	// +optional
+	Container *ContainerSpec `json:"container,omitempty"`
+	// +optional
+	ServerPort int32 `json:"serverPort,omitempty"`
+	// Time is when the revision was applied.
+	Time    metav1.Time     `json:"time"`
+	Outcome RevisionOutcome `json:"outcome"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// StaticStatus reports where a static game is served
+type StaticStatus struct {
+	// Pool is the name of the shared nginx Deployment serving the game.
+	Pool string `json:"pool"`
+	// Port is the port of the shared pods serving the game, the target port of the game Service.
+	Port int32 `json:"port"`
+}
+
+// IdlePhase is whether an idle game is running
+// +kubebuilder:validation:Enum=Awake;Idle
+type IdlePhase string
+
+const (
+	// IdlePhaseAwake means the game runs its replicas and receives requests through the activator.
+	IdlePhaseAwake IdlePhase = "Awake"
+	// IdlePhaseIdle means the game is scaled to zero until the activator receives a request for it.
+	IdlePhaseIdle IdlePhase = "Idle"
+)
+
+// IdleStatus reports the idle state of a game with spec.idle
+type IdleStatus struct {
+	Phase IdlePhase `json:"phase"`
+	// LastTransitionTime is when the game was last scaled to zero or woken.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// LastRequestTime is when the activator last received a request for the game, it is written about every 30 seconds.
+	// +optional
+	LastRequestTime *metav1.Time `json:"lastRequestTime,omitempty"`
+}
+
+// ScheduleStatus reports the schedules of the game
+type ScheduleStatus struct {
+	// Active is the schedule setting the replicas of the game, empty when spec.scaling applies.
+	// +optional
+	Active string `json:"active,omitempty"`
+	// ActiveSince is when the active schedule started.
+	// +optional
+	ActiveSince *metav1.Time `json:"activeSince,omitempty"`
+	// Replicas set by the active schedule.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// NextTransitionTime is when the next schedule starts or the active one ends.
+	// +optional
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty"`
+	// NextSchedule is the schedule starting at NextTransitionTime, empty when the active schedule ends then.
+	// +optional
+	NextSchedule string `json:"nextSchedule,omitempty"`
+}
+
+// ArchivePhase is the progress of the archive step
+// +kubebuilder:validation:Enum=Pending;Running;Succeeded;Failed
+type ArchivePhase string
+
+const (
+	// ArchivePending means the archive job has not started yet.
+	ArchivePending ArchivePhase = "Pending"
+	// ArchiveRunning means the archive job is running.
+	ArchiveRunning ArchivePhase = "Running"
+	// ArchiveSucceeded means the game data was archived and the WebGame can be released.
+	ArchiveSucceeded ArchivePhase = "Succeeded"
+	// ArchiveFailed means the archive job failed, the WebGame is kept until the policy is changed.
+	ArchiveFailed ArchivePhase = "Failed"
+)
+
+// ArchiveStatus reports how far the archive step has got
+type ArchiveStatus struct {
+	Phase ArchivePhase `json:"phase"`
+	// JobName is the name of the archive job.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DriftedField is a field the controller applies to a child resource which another manager changed
+type DriftedField struct {
+	// Kind of the child resource.
+	Kind string `json:"kind"`
+	// Name of the child resource.
+	Name string `json:"name"`
+	// Field is the path of the field, like .spec.replicas.
+	Field string `json:"field"`
+	// Manager is the field manager which changed the field.
+	Manager string `json:"manager"`
+}
+
+// WebGameStatus defines the observed state of WebGame
+type WebGameStatus struct {
+	DeploymentStatus appsv1.DeploymentStatus `json:"deploymentStatus,omitempty"`
+	GameAddress      string                  `json:"gameAddress,omitempty"`
+	ClusterIP        string                  `json:"clusterIP,omitempty"`
+	// Replicas is the number of game pods, read by the scale subresource.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// Selector is the label selector of the game pods, read by the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// ReplacedSelector is the selector of the pods of a Deployment replaced after a change of spec.gameType,
+	// the game Service selects them until the new Deployment is available.
+	// +optional
+	ReplacedSelector map[string]string `json:"replacedSelector,omitempty"`
+	// DisruptionsAllowed is the number of game pods the PodDisruptionBudget currently allows to be evicted,
+	// unset when the game has no budget.
+	// +optional
+	DisruptionsAllowed *int32 `json:"disruptionsAllowed,omitempty"`
+	// Resources are the requests and limits applied to the game container, resolved from the size preset and spec resources.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// ObservedGeneration is the most recent WebGame generation handled by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Phase summarizes the conditions below.
+	// +optional
+	Phase WebGamePhase `json:"phase,omitempty"`
+	// LastError is the error returned by the last failed reconcile, cleared on success.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// Conditions represent the latest available observations of the WebGame state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Archive reports the progress of the archive step while the WebGame is being deleted.
+	// +optional
+	Archive *ArchiveStatus `json:"archive,omitempty"`
+	// Rollout reports the last canary rollout, only set with the canary strategy.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+	// History are the last revisions applied to the game Deployment, the newest last.
+	// +optional
+	History []Revision `json:"history,omitempty"`
+	// Static reports the shared nginx Deployment serving a static game.
+	// +optional
+	Static *StaticStatus `json:"static,omitempty"`
+	// Schedule reports the active schedule and the next transition of a game with spec.schedules.
+	// +optional
+	Schedule *ScheduleStatus `json:"schedule,omitempty"`
+	// Idle reports whether a game with spec.idle is scaled to zero.
+	// +optional
+	Idle *IdleStatus `json:"idle,omitempty"`
+	// Drift are the fields of the child resources other managers changed and the drift policy left as they are.
+	// +optional
+	Drift []DriftedField `json:"drift,omitempty"`
+	// Template is the GameTemplate merged under the spec, empty when the game type has none.
+	// +optional
+	Template string `json:"template,omitempty"`
+	// EffectiveSpec is the spec the game was last reconciled with: the WebGame spec
+	// merged over its GameTemplate and the cluster defaults.
+	// +optional
+	EffectiveSpec *WebGameSpec `json:"effectiveSpec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.scaling.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:storageversion
+// +kubebuilder:resource:shortName=wg
+// +kubebuilder:printcolumn:name="DisplayName",type="string",JSONPath=".spec.displayName"
+// +kubebuilder:printcolumn:name="GameType",type="string",JSONPath=".spec.gameType"
+// +kubebuilder:printcolumn:name="ServerPort",type="integer",JSONPath=".spec.networking.serverPort"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".spec.scaling.replicas"
+// +kubebuilder:printcolumn:name="Size",type="string",JSONPath=".spec.container.size",priority=1
+// +kubebuilder:printcolumn:name="Available",type="integer",JSONPath=".status.deploymentStatus.availableReplicas"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.deploymentStatus.readyReplicas"
+// +kubebuilder:printcolumn:name="Updated",type="integer",JSONPath=".status.deploymentStatus.updatedReplicas"
+// +kubebuilder:printcolumn:name="Observed",type="integer",JSONPath=".status.deploymentStatus.observedGeneration"
+// +kubebuilder:printcolumn:name="Disruptions",type="integer",JSONPath=".status.disruptionsAllowed",priority=1
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".status.schedule.active",priority=1
+// +kubebuilder:printcolumn:name="Idle",type="string",JSONPath=".status.idle.phase",priority=1
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason",priority=1
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// WebGame is the Schema for the webgames API
+type WebGame struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WebGameSpec   `json:"spec,omitempty"`
+	Status WebGameStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WebGameList contains a list of WebGame
+type WebGameList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WebGame `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WebGame{}, &WebGameList{})
+}
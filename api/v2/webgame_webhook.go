@@ -0,0 +1,449 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var webgamelog = logf.Log.WithName("webgame-resource")
+
+// WebhookDefaults is the cluster policy applied by the defaulting webhook to fields left empty
+// by the user. IngressClass applies to the games whose GameTemplate sets no ingress class.
+// +kubebuilder:object:generate=false
+type WebhookDefaults struct {
+	Domain       string
+	IngressClass string
+	Replicas     int32
+}
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks with the manager,
+// together with the conversion webhook since WebGame is the conversion hub.
+func (r *WebGame) SetupWebhookWithManager(mgr ctrl.Manager, defaults WebhookDefaults) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&webGameDefaulter{reader: mgr.GetClient(), defaults: defaults}).
+		WithValidator(&webGameValidator{reader: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-webgame-webgame-tech-v2-webgame,mutating=true,failurePolicy=fail,sideEffects=None,groups=webgame.webgame.tech,resources=webgames,verbs=create;update,versions=v2,name=mwebgame.kb.io,admissionReviewVersions=v1
+
+// webGameDefaulter applies the cluster policy to the WebGames.
+type webGameDefaulter struct {
+	// reader reads the GameTemplates.
+	reader   client.Reader
+	defaults WebhookDefaults
+}
+
+var _ webhook.CustomDefaulter = &webGameDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (d *webGameDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	r, ok := obj.(*WebGame)
+	if !ok {
+		return fmt.Errorf("expected a WebGame but got a %T", obj)
+	}
+	webgamelog.V(2).Info("default", "namespace", r.GetNamespace(), "name", r.GetName())
+
+	// the image, the server port and the index page are left to the GameTemplate of the game type
+	if r.Spec.Networking.IngressClass == "" {
+		class, err := d.ingressClass(ctx, r.Spec.GameType)
+		if err != nil {
+			return err
+		}
+		r.Spec.Networking.IngressClass = class
+	}
+	if r.Spec.Routing.Domain == "" {
+		r.Spec.Routing.Domain = d.defaults.Domain
+	}
+	if r.Spec.Routing.Mode == "" {
+		r.Spec.Routing.Mode = RoutingModePath
+	}
+	if tls := r.Spec.Routing.TLS; tls != nil {
+		if tls.Mode == TLSModeCertManager && tls.SecretName == "" {
+			tls.SecretName = r.GetName() + "-tls"
+		}
+		if tls.Issuer != nil && tls.Issuer.Kind == "" {
+			tls.Issuer.Kind = "Issuer"
+		}
+		if tls.RedirectHTTP == nil {
+			redirect := true
+			tls.RedirectHTTP = &redirect
+		}
+		if tls.HSTS != nil && tls.HSTS.MaxAge == 0 {
+			tls.HSTS.MaxAge = 31536000
+		}
+	}
+	if r.Spec.Scaling.Replicas == nil {
+		replicas := d.defaults.Replicas
+		r.Spec.Scaling.Replicas = &replicas
+	}
+	if r.Spec.Idle != nil && r.Spec.Idle.Timeout.Duration == 0 {
+		r.Spec.Idle.Timeout.Duration = 30 * time.Minute
+	}
+	if r.Spec.DriftPolicy == "" {
+		r.Spec.DriftPolicy = DriftPolicyCorrect
+	}
+	if r.Spec.DeletionPolicy == "" {
+		r.Spec.DeletionPolicy = DeletionPolicyDelete
+	}
+	return nil
+}
+
+// ingressClass returns the ingress class of the GameTemplate of gameType, else the one of the cluster policy.
+func (d *webGameDefaulter) ingressClass(ctx context.Context, gameType string) (string, error) {
+	template, err := gameTemplate(ctx, d.reader, gameType)
+	if err != nil {
+		return "", err
+	}
+	if template.Spec.Defaults.IngressClass != "" {
+		return template.Spec.Defaults.IngressClass, nil
+	}
+	return d.defaults.IngressClass, nil
+}
+
+// gameTemplate returns the GameTemplate of gameType, an empty one when the game type has none.
+func gameTemplate(ctx context.Context, reader client.Reader, gameType string) (*GameTemplate, error) {
+	template := &GameTemplate{}
+	if gameType == "" {
+		return template, nil
+	}
+	if err := reader.Get(ctx, client.ObjectKey{Name: gameType}, template); err != nil {
+		if apierrors.IsNotFound(err) {
+			return &GameTemplate{}, nil
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+// +kubebuilder:webhook:path=/validate-webgame-webgame-tech-v2-webgame,mutating=false,failurePolicy=fail,sideEffects=None,groups=webgame.webgame.tech,resources=webgames,verbs=create;update,versions=v2,name=vwebgame.kb.io,admissionReviewVersions=v1
+
+// webGameValidator validates the WebGames.
+type webGameValidator struct {
+	// reader reads the GameTemplates.
+	reader client.Reader
+}
+
+var _ webhook.CustomValidator = &webGameValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *webGameValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	r, ok := obj.(*WebGame)
+	if !ok {
+		return nil, fmt.Errorf("expected a WebGame but got a %T", obj)
+	}
+	webgamelog.V(2).Info("validate create", "namespace", r.GetNamespace(), "name", r.GetName())
+	return r.Spec.warnings(), v.validate(ctx, r, true)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *webGameValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	r, ok := newObj.(*WebGame)
+	if !ok {
+		return nil, fmt.Errorf("expected a WebGame but got a %T", newObj)
+	}
+	webgamelog.V(2).Info("validate update", "namespace", r.GetNamespace(), "name", r.GetName())
+	warnings := r.Spec.warnings()
+	// the selector of a deployment is immutable, the controller replaces it
+	if previous, ok := oldObj.(*WebGame); ok && previous.Spec.GameType != r.Spec.GameType {
+		warnings = append(warnings, "spec.gameType selects the game pods, the game Deployment is replaced")
+	}
+	return warnings, v.validate(ctx, r, false)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *webGameValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// warnings returns the warnings of the spec which do not prevent the game from being reconciled.
+func (s *WebGameSpec) warnings() admission.Warnings {
+	var warnings admission.Warnings
+	if s.Idle != nil && s.Routing.Backend == RoutingBackendGateway {
+		warnings = append(warnings, "spec.idle routes the game through an ExternalName Service, which not every Gateway implementation resolves")
+	}
+	if s.Routing.TLS != nil && s.Routing.Backend == RoutingBackendGateway {
+		warnings = append(warnings, "spec.routing.tls only sets the hostname of the HTTPRoute, the Gateway listener serves the certificate")
+	}
+	return warnings
+}
+
+// validate validates the spec of r, and its image and named serverPort which need the GameTemplate and the
+// annotations of the WebGame.
+func (v *webGameValidator) validate(ctx context.Context, r *WebGame, create bool) error {
+	path := field.NewPath("spec")
+	errs := r.Spec.validate(path)
+
+	// the WebGames written as v1 before the webhooks keep their named port, new ones cannot use one
+	if port, ok := r.GetAnnotations()[V1ServerPortAnnotation]; ok && create && r.Spec.Networking.ServerPort == 0 {
+		errs = append(errs, field.Invalid(path.Child("networking", "serverPort"), port, "named ports are not supported, must be a port number"))
+	}
+	if r.Spec.Container.Image == "" && (r.Spec.Source == nil || r.Spec.Source.Static == nil) {
+		template, err := gameTemplate(ctx, v.reader, r.Spec.GameType)
+		if err != nil {
+			return err
+		}
+		if template.Spec.Defaults.Image == "" {
+			errs = append(errs, field.Required(path.Child("container", "image"), fmt.Sprintf("required when the GameTemplate of the game type %q sets no image", r.Spec.GameType)))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("WebGame").GroupKind(), r.GetName(), errs)
+}
+
+func (s *WebGameSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if s.DisplayName == "" {
+		errs = append(errs, field.Required(path.Child("displayName"), ""))
+	}
+
+	// gameType is used as a label value and as a path segment of the game address
+	if s.GameType == "" {
+		errs = append(errs, field.Required(path.Child("gameType"), ""))
+	}
+	for _, msg := range validation.IsValidLabelValue(s.GameType) {
+		errs = append(errs, field.Invalid(path.Child("gameType"), s.GameType, msg))
+	}
+
+	if s.Source != nil && s.Source.Static != nil {
+		errs = append(errs, s.Source.Static.validate(path.Child("source", "static"))...)
+		// the shared nginx pods have no game container to roll out or scale on their own
+		if s.Container.Image != "" {
+			errs = append(errs, field.Forbidden(path.Child("container", "image"), "static games are served by the shared nginx image"))
+		}
+		if s.Rollout.Canary != nil {
+			errs = append(errs, field.Forbidden(path.Child("rollout", "canary"), "not supported by static games"))
+		}
+		if s.Scaling.Autoscaling != nil {
+			errs = append(errs, field.Forbidden(path.Child("scaling", "autoscaling"), "not supported by static games"))
+		}
+		if s.RollbackTo != nil {
+			errs = append(errs, field.Forbidden(path.Child("rollbackTo"), "static games have no revision history"))
+		}
+		if s.Idle != nil {
+			errs = append(errs, field.Forbidden(path.Child("idle"), "static games share their pods with other games"))
+		}
+	}
+	if s.Idle != nil {
+		if s.Idle.Timeout.Duration < time.Minute {
+			errs = append(errs, field.Invalid(path.Child("idle", "timeout"), s.Idle.Timeout.Duration.String(), "must be at least 1m"))
+		}
+		// requests go through the activator, which does not split traffic with a canary
+		if s.Rollout.Canary != nil {
+			errs = append(errs, field.Forbidden(path.Child("idle"), "idle games cannot use the canary strategy"))
+		}
+	}
+	errs = append(errs, s.Container.validate(path.Child("container"))...)
+	errs = append(errs, s.Networking.validate(path.Child("networking"))...)
+	errs = append(errs, s.Routing.validate(path.Child("routing"))...)
+	errs = append(errs, s.Scaling.validate(path.Child("scaling"))...)
+	names := map[string]bool{}
+	for i := range s.Schedules {
+		schedule := &s.Schedules[i]
+		errs = append(errs, schedule.validate(path.Child("schedules").Index(i))...)
+		if names[schedule.Name] {
+			errs = append(errs, field.Duplicate(path.Child("schedules").Index(i).Child("name"), schedule.Name))
+		}
+		names[schedule.Name] = true
+	}
+	if s.RollbackTo != nil && *s.RollbackTo < 1 {
+		errs = append(errs, field.Invalid(path.Child("rollbackTo"), *s.RollbackTo, "must be greater than or equal to 1"))
+	}
+	if s.Rollout.Canary != nil {
+		errs = append(errs, s.Rollout.Canary.validate(path.Child("rollout", "canary"))...)
+	}
+
+	if d := s.Disruption; d != nil && d.MinAvailable != nil && d.MaxUnavailable != nil {
+		errs = append(errs, field.Forbidden(path.Child("disruption", "maxUnavailable"), "minAvailable and maxUnavailable cannot both be set"))
+	}
+
+	if s.DeletionPolicy == DeletionPolicyArchive {
+		if s.Archive == nil {
+			errs = append(errs, field.Required(path.Child("archive"), fmt.Sprintf("required when deletionPolicy is %s", DeletionPolicyArchive)))
+		} else if s.Archive.Destination == "" {
+			errs = append(errs, field.Required(path.Child("archive", "destination"), ""))
+		}
+	}
+
+	return errs
+}
+
+func (s *StaticSource) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	var set []string
+	if s.ConfigMap != nil {
+		set = append(set, "configMap")
+		if s.ConfigMap.Name == "" {
+			errs = append(errs, field.Required(path.Child("configMap", "name"), ""))
+		}
+	}
+	if s.Secret != nil {
+		set = append(set, "secret")
+		if s.Secret.Name == "" {
+			errs = append(errs, field.Required(path.Child("secret", "name"), ""))
+		}
+	}
+	if s.ArchiveURL != "" {
+		set = append(set, "archiveURL")
+		if u, err := url.Parse(s.ArchiveURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, field.Invalid(path.Child("archiveURL"), s.ArchiveURL, "must be an absolute http or https URL"))
+		}
+	}
+	switch {
+	case len(set) == 0:
+		errs = append(errs, field.Required(path, "one of configMap, secret and archiveURL is required"))
+	case len(set) > 1:
+		errs = append(errs, field.Forbidden(path.Child(set[1]), fmt.Sprintf("only one of configMap, secret and archiveURL can be set, %s is set", set[0])))
+	}
+	return errs
+}
+
+func (s *ContainerSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, secret := range s.ImagePullSecrets {
+		if secret.Name == "" {
+			errs = append(errs, field.Required(path.Child("imagePullSecrets").Index(i).Child("name"), ""))
+		}
+	}
+	for name, request := range s.Resources.Requests {
+		if limit, ok := s.Resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(path.Child("resources", "requests").Key(string(name)), request.String(),
+				fmt.Sprintf("must be less than or equal to %s limit of %s", name, limit.String())))
+		}
+	}
+	return errs
+}
+
+func (s *NetworkingSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if s.ServerPort != 0 {
+		for _, msg := range validation.IsValidPortNum(int(s.ServerPort)) {
+			errs = append(errs, field.Invalid(path.Child("serverPort"), s.ServerPort, msg))
+		}
+	}
+	return errs
+}
+
+func (s *RoutingSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(s.Domain) {
+		errs = append(errs, field.Invalid(path.Child("domain"), s.Domain, msg))
+	}
+	if s.IndexPage != "" && !strings.HasPrefix(s.IndexPage, "/") {
+		errs = append(errs, field.Invalid(path.Child("indexPage"), s.IndexPage, "must start with '/'"))
+	}
+	if s.TLS != nil {
+		errs = append(errs, s.TLS.validate(path.Child("tls"))...)
+	}
+	if s.Gateway != nil && s.Gateway.Name == "" {
+		errs = append(errs, field.Required(path.Child("gateway", "name"), ""))
+	}
+	return errs
+}
+
+func (s *TLSSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch s.Mode {
+	case TLSModeSecret:
+		if s.SecretName == "" {
+			errs = append(errs, field.Required(path.Child("secretName"), fmt.Sprintf("required when mode is %s", s.Mode)))
+		}
+	case TLSModeCertManager:
+		if s.Issuer == nil || s.Issuer.Name == "" {
+			errs = append(errs, field.Required(path.Child("issuer", "name"), fmt.Sprintf("required when mode is %s", s.Mode)))
+		}
+	case TLSModeWildcard:
+		if s.SecretName != "" {
+			errs = append(errs, field.Forbidden(path.Child("secretName"), "the wildcard certificate is set by the platform configuration"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("mode"), s.Mode, []string{string(TLSModeSecret), string(TLSModeWildcard), string(TLSModeCertManager)}))
+	}
+	if s.SecretName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(s.SecretName) {
+			errs = append(errs, field.Invalid(path.Child("secretName"), s.SecretName, msg))
+		}
+	}
+	return errs
+}
+
+func (s *ScalingSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if s.Replicas == nil {
+		errs = append(errs, field.Required(path.Child("replicas"), ""))
+	} else if *s.Replicas < 0 {
+		errs = append(errs, field.Invalid(path.Child("replicas"), *s.Replicas, "must be greater than or equal to 0"))
+	}
+	if a := s.Autoscaling; a != nil {
+		if a.MaxReplicas < 1 {
+			errs = append(errs, field.Invalid(path.Child("autoscaling", "maxReplicas"), a.MaxReplicas, "must be greater than or equal to 1"))
+		}
+		if a.MinReplicas != nil && *a.MinReplicas > a.MaxReplicas {
+			errs = append(errs, field.Invalid(path.Child("autoscaling", "minReplicas"), *a.MinReplicas, "must be less than or equal to maxReplicas"))
+		}
+	}
+	return errs
+}
+
+func (s *ScheduleSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if s.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), ""))
+	}
+	if _, err := cron.ParseStandard(s.Schedule); err != nil {
+		errs = append(errs, field.Invalid(path.Child("schedule"), s.Schedule, err.Error()))
+	}
+	// the time zone is set on its own, the controller does not accept it in the expression
+	if strings.Contains(s.Schedule, "TZ") {
+		errs = append(errs, field.Invalid(path.Child("schedule"), s.Schedule, "set the time zone in timeZone"))
+	}
+	if _, err := time.LoadLocation(s.TimeZone); err != nil {
+		errs = append(errs, field.Invalid(path.Child("timeZone"), s.TimeZone, err.Error()))
+	}
+	if s.Replicas < 0 {
+		errs = append(errs, field.Invalid(path.Child("replicas"), s.Replicas, "must be greater than or equal to 0"))
+	}
+	if s.Duration != nil && s.Duration.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("duration"), s.Duration.Duration.String(), "must be positive"))
+	}
+	return errs
+}
+
+func (s *CanaryStrategy) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(s.Steps) == 0 {
+		errs = append(errs, field.Required(path.Child("steps"), ""))
+	}
+	for i, step := range s.Steps {
+		if step.Weight < 0 || step.Weight > 100 {
+			errs = append(errs, field.Invalid(path.Child("steps").Index(i).Child("weight"), step.Weight, "must be between 0 and 100"))
+		}
+		if step.Pause != nil && step.Pause.Duration < 0 {
+			errs = append(errs, field.Invalid(path.Child("steps").Index(i).Child("pause"), step.Pause.Duration.String(), "must not be negative"))
+		}
+	}
+	if a := s.Analysis; a != nil && a.Interval.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("analysis", "interval"), a.Interval.Duration.String(), "must be positive"))
+	}
+	return errs
+}
@@ -0,0 +1,302 @@
+package v2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newReader returns a client holding the GameTemplate of the tetris game type.
+func newReader(t *testing.T) client.Reader {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	template := &GameTemplate{}
+	template.SetName("tetris")
+	template.Spec.Defaults = GameDefaults{Image: "webgamedevelop/tetris:latest", IngressClass: "traefik"}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(template).Build()
+}
+
+func newWebGame() *WebGame {
+	replicas := int32(1)
+	webgame := &WebGame{}
+	webgame.SetNamespace("webgames")
+	webgame.SetName("webgame-2048")
+	webgame.Spec = WebGameSpec{
+		DisplayName: "2048",
+		GameType:    "2048",
+		Container:   ContainerSpec{Image: "webgamedevelop/2048:latest"},
+		Networking:  NetworkingSpec{ServerPort: 80, IngressClass: "nginx"},
+		Routing:     RoutingSpec{Domain: "games.example.com", IndexPage: "/index.html"},
+		Scaling:     ScalingSpec{Replicas: &replicas},
+	}
+	return webgame
+}
+
+func TestDefault(t *testing.T) {
+	defaults := WebhookDefaults{Domain: "localhost", IngressClass: "nginx", Replicas: 2}
+	tests := []struct {
+		name   string
+		mutate func(webgame *WebGame)
+		check  func(t *testing.T, spec *WebGameSpec)
+	}{{
+		name:   "spec values are kept",
+		mutate: func(*WebGame) {},
+		check: func(t *testing.T, spec *WebGameSpec) {
+			if spec.Routing.Domain != "games.example.com" || spec.Networking.IngressClass != "nginx" || *spec.Scaling.Replicas != 1 {
+				t.Errorf("spec changed: %+v", spec)
+			}
+		},
+	}, {
+		name: "cluster policy",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Routing.Domain = ""
+			webgame.Spec.Networking.IngressClass = ""
+			webgame.Spec.Scaling.Replicas = nil
+		},
+		check: func(t *testing.T, spec *WebGameSpec) {
+			if spec.Routing.Domain != "localhost" {
+				t.Errorf("domain = %s, want localhost", spec.Routing.Domain)
+			}
+			if spec.Networking.IngressClass != "nginx" {
+				t.Errorf("ingressClass = %s, want nginx", spec.Networking.IngressClass)
+			}
+			if spec.Scaling.Replicas == nil || *spec.Scaling.Replicas != 2 {
+				t.Errorf("replicas = %v, want 2", spec.Scaling.Replicas)
+			}
+		},
+	}, {
+		name: "ingress class of the template",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.GameType = "tetris"
+			webgame.Spec.Networking.IngressClass = ""
+		},
+		check: func(t *testing.T, spec *WebGameSpec) {
+			if spec.Networking.IngressClass != "traefik" {
+				t.Errorf("ingressClass = %s, want traefik", spec.Networking.IngressClass)
+			}
+		},
+	}, {
+		name:   "policies",
+		mutate: func(*WebGame) {},
+		check: func(t *testing.T, spec *WebGameSpec) {
+			if spec.Routing.Mode != RoutingModePath || spec.DriftPolicy != DriftPolicyCorrect || spec.DeletionPolicy != DeletionPolicyDelete {
+				t.Errorf("mode = %s, driftPolicy = %s, deletionPolicy = %s", spec.Routing.Mode, spec.DriftPolicy, spec.DeletionPolicy)
+			}
+		},
+	}, {
+		name: "cert-manager tls",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Routing.TLS = &TLSSpec{Mode: TLSModeCertManager, Issuer: &IssuerReference{Name: "letsencrypt"}, HSTS: &HSTSSpec{}}
+		},
+		check: func(t *testing.T, spec *WebGameSpec) {
+			tls := spec.Routing.TLS
+			if tls.SecretName != "webgame-2048-tls" || tls.Issuer.Kind != "Issuer" || tls.RedirectHTTP == nil || !*tls.RedirectHTTP || tls.HSTS.MaxAge != 31536000 {
+				t.Errorf("tls = %+v", tls)
+			}
+		},
+	}, {
+		name: "idle timeout",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Idle = &IdleSpec{}
+		},
+		check: func(t *testing.T, spec *WebGameSpec) {
+			if spec.Idle.Timeout.Duration != 30*time.Minute {
+				t.Errorf("idle timeout = %s, want 30m", spec.Idle.Timeout.Duration)
+			}
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webgame := newWebGame()
+			tt.mutate(webgame)
+			d := &webGameDefaulter{reader: newReader(t), defaults: defaults}
+			if err := d.Default(context.Background(), webgame); err != nil {
+				t.Fatalf("Default: %v", err)
+			}
+			tt.check(t, &webgame.Spec)
+		})
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(webgame *WebGame)
+		// fields are the invalid fields, none when the WebGame is valid
+		fields []string
+	}{{
+		name:   "valid",
+		mutate: func(*WebGame) {},
+	}, {
+		name: "image of the template",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.GameType = "tetris"
+			webgame.Spec.Container.Image = ""
+		},
+	}, {
+		name: "empty image",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Container.Image = ""
+		},
+		fields: []string{"spec.container.image"},
+	}, {
+		name: "named server port",
+		mutate: func(webgame *WebGame) {
+			webgame.SetAnnotations(map[string]string{V1ServerPortAnnotation: "http"})
+			webgame.Spec.Networking.ServerPort = 0
+		},
+		fields: []string{"spec.networking.serverPort"},
+	}, {
+		name: "server port out of range",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Networking.ServerPort = 70000
+		},
+		fields: []string{"spec.networking.serverPort"},
+	}, {
+		name: "bad domain",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Routing.Domain = "Games_Example"
+		},
+		fields: []string{"spec.routing.domain"},
+	}, {
+		name: "relative index page",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Routing.IndexPage = "index.html"
+		},
+		fields: []string{"spec.routing.indexPage"},
+	}, {
+		name: "missing names",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.DisplayName = ""
+			webgame.Spec.Container.ImagePullSecrets = []corev1.LocalObjectReference{{}}
+		},
+		fields: []string{"spec.displayName", "spec.container.imagePullSecrets[0].name"},
+	}, {
+		name: "tls secret mode without secret",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Routing.TLS = &TLSSpec{Mode: TLSModeSecret}
+		},
+		fields: []string{"spec.routing.tls.secretName"},
+	}, {
+		name: "autoscaling bounds",
+		mutate: func(webgame *WebGame) {
+			minReplicas := int32(5)
+			webgame.Spec.Scaling.Autoscaling = &AutoscalingSpec{MinReplicas: &minReplicas, MaxReplicas: 2}
+		},
+		fields: []string{"spec.scaling.autoscaling.minReplicas"},
+	}, {
+		name: "static game with image",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Source = &SourceSpec{Static: &StaticSource{ArchiveURL: "https://games.example.com/2048.zip"}}
+		},
+		fields: []string{"spec.container.image"},
+	}, {
+		name: "archive policy without archive",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.DeletionPolicy = DeletionPolicyArchive
+		},
+		fields: []string{"spec.archive"},
+	}, {
+		name: "canary weight",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Rollout.Canary = &CanaryStrategy{Steps: []CanaryStep{{Weight: 120}}}
+		},
+		fields: []string{"spec.rollout.canary.steps[0].weight"},
+	}, {
+		name: "idle timeout",
+		mutate: func(webgame *WebGame) {
+			webgame.Spec.Idle = &IdleSpec{Timeout: metav1.Duration{Duration: time.Second}}
+		},
+		fields: []string{"spec.idle.timeout"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webgame := newWebGame()
+			tt.mutate(webgame)
+			v := &webGameValidator{reader: newReader(t)}
+			_, err := v.ValidateCreate(context.Background(), webgame)
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateCreate: %v", err)
+				}
+				return
+			}
+			if !apierrors.IsInvalid(err) {
+				t.Fatalf("ValidateCreate error = %v, want invalid", err)
+			}
+			var fields []string
+			for _, cause := range err.(*apierrors.StatusError).ErrStatus.Details.Causes {
+				fields = append(fields, cause.Field)
+			}
+			if len(fields) != len(tt.fields) {
+				t.Fatalf("invalid fields = %v, want %v", fields, tt.fields)
+			}
+			for i := range fields {
+				if fields[i] != tt.fields[i] {
+					t.Errorf("invalid fields = %v, want %v", fields, tt.fields)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	old := newWebGame()
+	old.SetAnnotations(map[string]string{V1ServerPortAnnotation: "http"})
+	old.Spec.Networking.ServerPort = 0
+	webgame := old.DeepCopy()
+	webgame.Spec.GameType = "2048-v2"
+
+	v := &webGameValidator{reader: newReader(t)}
+	warnings, err := v.ValidateUpdate(context.Background(), old, webgame)
+	// a WebGame written as v1 with a named port can still be updated
+	if err != nil {
+		t.Fatalf("ValidateUpdate: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %v, want the replaced Deployment", warnings)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(spec *WebGameSpec)
+		warnings int
+	}{{
+		name:   "ingress",
+		mutate: func(spec *WebGameSpec) { spec.Routing.TLS = &TLSSpec{Mode: TLSModeSecret, SecretName: "games-tls"} },
+	}, {
+		name: "gateway tls",
+		mutate: func(spec *WebGameSpec) {
+			spec.Routing.Backend = RoutingBackendGateway
+			spec.Routing.TLS = &TLSSpec{Mode: TLSModeSecret, SecretName: "games-tls"}
+		},
+		warnings: 1,
+	}, {
+		name: "gateway idle",
+		mutate: func(spec *WebGameSpec) {
+			spec.Routing.Backend = RoutingBackendGateway
+			spec.Idle = &IdleSpec{}
+		},
+		warnings: 1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webgame := newWebGame()
+			tt.mutate(&webgame.Spec)
+			if warnings := webgame.Spec.warnings(); len(warnings) != tt.warnings {
+				t.Errorf("warnings = %v, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+}
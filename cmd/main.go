@@ -4,9 +4,11 @@ import (
 	"context"
 	"flag"
 	"os"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/webgamedevelop/logger"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -16,14 +18,24 @@ import (
 	"k8s.io/component-base/version/verflag"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
 	webgamev1 "github.com/webgamedevelop/webgame/api/v1"
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/catalog"
+	"github.com/webgamedevelop/webgame/internal/certs"
+	"github.com/webgamedevelop/webgame/internal/config"
 	"github.com/webgamedevelop/webgame/internal/controller"
+	"github.com/webgamedevelop/webgame/internal/migration"
 	// +kubebuilder:scaffold:imports
 )
 
+const webgameCRD = "webgames.webgame.webgame.tech"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -31,21 +43,45 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 
 	utilruntime.Must(webgamev1.AddToScheme(scheme))
+	utilruntime.Must(webgamev2.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
+	var catalogAddr string
+	var activatorAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var configFile string
 	pflag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	pflag.StringVar(&catalogAddr, "catalog-bind-address", "0", "The address the read-only game catalog API binds to. Set this to \"0\" to disable the catalog.")
+	pflag.StringVar(&activatorAddr, "activator-bind-address", "0", "The address the activator waking idle games binds to. Set this to \"0\" to disable the activator.")
 	pflag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	pflag.StringVar(&configFile, "config", "", "The controller configuration file, built-in defaults are used when empty.")
 	pflag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 
+	var enableWebhooks bool
+	var webhookDefaults webgamev2.WebhookDefaults
+	var certOptions = certs.Options{
+		MutatingWebhooks:          []string{"webgame-mutating-webhook-configuration"},
+		ValidatingWebhooks:        []string{"webgame-validating-webhook-configuration"},
+		CustomResourceDefinitions: []string{webgameCRD},
+	}
+	pflag.BoolVar(&enableWebhooks, "enable-webhooks", true, "Serve the defaulting and validating webhooks of the WebGame API. The conversion webhook serving v1 is always served.")
+	pflag.StringVar(&certOptions.CertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "The directory the webhook serving certificate is written to.")
+	pflag.StringVar(&certOptions.Namespace, "webhook-namespace", envOrDefault("POD_NAMESPACE", "webgame-system"), "The namespace of the webhook service and certificate secret.")
+	pflag.StringVar(&certOptions.ServiceName, "webhook-service-name", "webgame-webhook-service", "The name of the webhook service.")
+	pflag.StringVar(&certOptions.SecretName, "webhook-secret-name", "webgame-webhook-server-cert", "The name of the secret storing the self-signed webhook certificate.")
+	pflag.StringVar(&webhookDefaults.IngressClass, "default-ingress-class", "nginx", "The ingress class of WebGames which do not set one and whose GameTemplate sets none.")
+	pflag.StringVar(&webhookDefaults.Domain, "default-domain", "localhost", "The domain of WebGames which do not set one.")
+	pflag.Int32Var(&webhookDefaults.Replicas, "default-replicas", 1, "The replicas of WebGames which do not set them.")
+
 	var versionFlag pflag.FlagSet
 	verflag.AddFlags(&versionFlag)
 
@@ -66,9 +102,17 @@ func main() {
 	setLogger(ctx)
 	defer klog.Flush()
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	controllerConfig, err := config.Load(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load controller configuration")
+		os.Exit(1)
+	}
+
+	cfg := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
+		WebhookServer:          webhook.NewServer(webhook.Options{Port: 9443, CertDir: certOptions.CertDir}),
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "199d8150.webgame.tech",
@@ -79,14 +123,75 @@ func main() {
 	}
 
 	if err = (&controller.WebGameReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Scheme:    mgr.GetScheme(),
+		Config:    controllerConfig,
+		Recorder:  mgr.GetEventRecorderFor("webgame-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "WebGame")
 		os.Exit(1)
 	}
+
+	// the CRD serves v1 through the conversion webhook, so the webhook server and its certificate are needed
+	// even when the admission webhooks are disabled. The manager client is not usable before the cache is started.
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+	if !enableWebhooks {
+		certOptions.MutatingWebhooks, certOptions.ValidatingWebhooks = nil, nil
+	}
+	if err = certs.Ensure(ctx, c, certOptions); err != nil {
+		setupLog.Error(err, "unable to set up webhook serving certificate")
+		os.Exit(1)
+	}
+	if err = mgr.Add(&certs.Rotator{Client: c, Options: certOptions, Interval: 24 * time.Hour}); err != nil {
+		setupLog.Error(err, "unable to set up webhook certificate rotation")
+		os.Exit(1)
+	}
+	mgr.GetWebhookServer().Register("/convert", conversion.NewWebhookHandler(mgr.GetScheme()))
+	if enableWebhooks {
+		if err = (&webgamev2.WebGame{}).SetupWebhookWithManager(mgr, webhookDefaults); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "WebGame")
+			os.Exit(1)
+		}
+	}
+	if err = mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+		setupLog.Error(err, "unable to set up webhook ready check")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
+	if err = mgr.Add(&migration.StorageVersionMigrator{
+		Client:  mgr.GetClient(),
+		Reader:  mgr.GetAPIReader(),
+		CRDName: webgameCRD,
+		GVK:     webgamev2.GroupVersion.WithKind("WebGameList"),
+	}); err != nil {
+		setupLog.Error(err, "unable to set up storage version migration")
+		os.Exit(1)
+	}
+
+	if catalogAddr != "0" {
+		if err = mgr.Add(&catalog.Server{Cache: mgr.GetCache(), BindAddress: catalogAddr}); err != nil {
+			setupLog.Error(err, "unable to set up game catalog")
+			os.Exit(1)
+		}
+	}
+
+	if activatorAddr != "0" {
+		if err = (&controller.Activator{
+			Client:      mgr.GetClient(),
+			Config:      controllerConfig,
+			BindAddress: activatorAddr,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to set up activator")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -103,6 +208,13 @@ func main() {
 	}
 }
 
+func envOrDefault(key, value string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return value
+}
+
 func setLogger(ctx context.Context) {
 	l, flush := logger.New(ctx, logger.DefaultEncoderConfig)
 	klog.SetLoggerWithOptions(l, klog.FlushLogger(flush))
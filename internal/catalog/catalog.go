@@ -0,0 +1,352 @@
+// Package catalog serves a read-only HTTP/JSON API of the WebGames, read from the manager cache,
+// so game portals can list the games without access to the Kubernetes API.
+package catalog
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+const (
+	// defaultLimit and maxLimit bound the number of games in a page.
+	defaultLimit = 100
+	maxLimit     = 500
+
+	// keepaliveInterval is how often an idle event stream sends a comment, so proxies keep it open.
+	keepaliveInterval = 30 * time.Second
+	shutdownTimeout   = 10 * time.Second
+)
+
+// Types of the events of the game stream.
+const (
+	EventAdded    = "ADDED"
+	EventModified = "MODIFIED"
+	EventDeleted  = "DELETED"
+)
+
+// Game is a WebGame as listed by the catalog.
+type Game struct {
+	Namespace     string                 `json:"namespace"`
+	Name          string                 `json:"name"`
+	DisplayName   string                 `json:"displayName"`
+	GameType      string                 `json:"gameType"`
+	Address       string                 `json:"address,omitempty"`
+	Ready         bool                   `json:"ready"`
+	Phase         webgamev2.WebGamePhase `json:"phase,omitempty"`
+	Replicas      int32                  `json:"replicas"`
+	ReadyReplicas int32                  `json:"readyReplicas"`
+	Labels        map[string]string      `json:"labels,omitempty"`
+}
+
+// GameList is a page of games. Continue is set when there are more games, it is passed
+// as the continue query parameter to get the next page.
+type GameList struct {
+	Items    []Game `json:"items"`
+	Continue string `json:"continue,omitempty"`
+}
+
+// Server serves the catalog API:
+//
+//	GET /games                      lists the games, filtered by the namespace, gameType and labelSelector
+//	                                query parameters and paginated by limit and continue
+//	GET /games?watch=true           streams the changes of the games as server-sent events
+//	GET /games/{namespace}/{name}   gets a game
+type Server struct {
+	// Cache is the manager cache the games are read from.
+	Cache cache.Cache
+	// BindAddress is the address the API listens on.
+	BindAddress string
+}
+
+// Start implements manager.Runnable.
+func (s *Server) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("catalog")
+	server := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// event streams end with the manager
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "unable to shut down the catalog server")
+		}
+	}()
+
+	logger.Info("serving game catalog", "address", s.BindAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica serves the catalog from its cache.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Handler returns the handler of the catalog API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/games", s.games)
+	mux.HandleFunc("/games/", s.game)
+	return mux
+}
+
+func (s *Server) games(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	query := r.URL.Query()
+	f, err := newFilter(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if watch, _ := strconv.ParseBool(query.Get("watch")); watch {
+		s.watch(w, r, f)
+		return
+	}
+
+	limit := defaultLimit
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		}
+		limit = min(limit, maxLimit)
+	}
+	after := ""
+	if token := query.Get("continue"); token != "" {
+		key, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid continue token"))
+			return
+		}
+		after = string(key)
+	}
+
+	var webgames webgamev2.WebGameList
+	if err := s.Cache.List(r.Context(), &webgames, client.InNamespace(f.namespace), client.MatchingLabelsSelector{Selector: f.selector}); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// the cache has no order, the games are paginated by namespace and name
+	sort.Slice(webgames.Items, func(i, j int) bool {
+		return key(&webgames.Items[i]) < key(&webgames.Items[j])
+	})
+
+	list := GameList{Items: []Game{}}
+	for i := range webgames.Items {
+		webgame := &webgames.Items[i]
+		if key(webgame) <= after || !f.matches(webgame) {
+			continue
+		}
+		if len(list.Items) == limit {
+			last := list.Items[len(list.Items)-1]
+			list.Continue = base64.RawURLEncoding.EncodeToString([]byte(last.Namespace + "/" + last.Name))
+			break
+		}
+		list.Items = append(list.Items, newGame(webgame))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) game(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/games/"), "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		writeError(w, http.StatusNotFound, fmt.Errorf("path %s is not a game, use /games/{namespace}/{name}", r.URL.Path))
+		return
+	}
+
+	var webgame webgamev2.WebGame
+	if err := s.Cache.Get(r.Context(), client.ObjectKey{Namespace: namespace, Name: name}, &webgame); err != nil {
+		if apierrors.IsNotFound(err) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("game %s/%s not found", namespace, name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newGame(&webgame))
+}
+
+// watch streams the games matching f as server-sent events, the event type is one of EventAdded,
+// EventModified and EventDeleted and its data the game. The stream starts with an EventAdded of every game.
+func (s *Server) watch(w http.ResponseWriter, r *http.Request, f filter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	ctx := r.Context()
+	informer, err := s.Cache.GetInformer(ctx, &webgamev2.WebGame{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type event struct {
+		eventType string
+		webgame   *webgamev2.WebGame
+	}
+	// every handler has its own buffer in the informer, blocking here only delays this stream
+	events := make(chan event)
+	send := func(eventType string, webgame *webgamev2.WebGame) {
+		select {
+		case events <- event{eventType, webgame}:
+		case <-ctx.Done():
+		}
+	}
+	registration, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if webgame, ok := obj.(*webgamev2.WebGame); ok && f.matches(webgame) {
+				send(EventAdded, webgame)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldWebGame, ok := oldObj.(*webgamev2.WebGame)
+			if !ok {
+				return
+			}
+			webgame, ok := newObj.(*webgamev2.WebGame)
+			if !ok || oldWebGame.GetResourceVersion() == webgame.GetResourceVersion() {
+				return
+			}
+			// a game entering or leaving the filter is added or deleted
+			switch oldMatches, matches := f.matches(oldWebGame), f.matches(webgame); {
+			case oldMatches && matches:
+				send(EventModified, webgame)
+			case matches:
+				send(EventAdded, webgame)
+			case oldMatches:
+				send(EventDeleted, webgame)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if webgame, ok := obj.(*webgamev2.WebGame); ok && f.matches(webgame) {
+				send(EventDeleted, webgame)
+			}
+		},
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer func() {
+		if err := informer.RemoveEventHandler(registration); err != nil {
+			log.FromContext(ctx).Error(err, "unable to remove the catalog event handler")
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case e := <-events:
+			data, err := json.Marshal(newGame(e.webgame))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.webgame.GetResourceVersion(), e.eventType, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// filter selects the games of a request.
+type filter struct {
+	namespace string
+	gameType  string
+	selector  labels.Selector
+}
+
+func newFilter(query url.Values) (filter, error) {
+	selector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		return filter{}, fmt.Errorf("invalid labelSelector: %w", err)
+	}
+	return filter{namespace: query.Get("namespace"), gameType: query.Get("gameType"), selector: selector}, nil
+}
+
+func (f filter) matches(webgame *webgamev2.WebGame) bool {
+	return (f.namespace == "" || webgame.GetNamespace() == f.namespace) &&
+		(f.gameType == "" || webgame.Spec.GameType == f.gameType) &&
+		f.selector.Matches(labels.Set(webgame.GetLabels()))
+}
+
+// key orders the games by namespace and name.
+func key(webgame *webgamev2.WebGame) string {
+	return webgame.GetNamespace() + "/" + webgame.GetName()
+}
+
+func newGame(webgame *webgamev2.WebGame) Game {
+	return Game{
+		Namespace:     webgame.GetNamespace(),
+		Name:          webgame.GetName(),
+		DisplayName:   webgame.Spec.DisplayName,
+		GameType:      webgame.Spec.GameType,
+		Address:       webgame.Status.GameAddress,
+		Ready:         meta.IsStatusConditionTrue(webgame.Status.Conditions, webgamev2.ConditionReady),
+		Phase:         webgame.Status.Phase,
+		Replicas:      webgame.Status.Replicas,
+		ReadyReplicas: webgame.Status.DeploymentStatus.ReadyReplicas,
+		Labels:        webgame.GetLabels(),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,207 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// fakeCache reads the games from a fake client, the catalog only lists and gets them outside of watches.
+type fakeCache struct {
+	cache.Cache
+	reader client.Reader
+}
+
+func (c *fakeCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.reader.Get(ctx, key, obj, opts...)
+}
+
+func (c *fakeCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.reader.List(ctx, list, opts...)
+}
+
+func newServer(t *testing.T) *Server {
+	scheme := runtime.NewScheme()
+	if err := webgamev2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	newWebGame := func(namespace, name, gameType string, labels map[string]string) client.Object {
+		webgame := &webgamev2.WebGame{}
+		webgame.SetNamespace(namespace)
+		webgame.SetName(name)
+		webgame.SetLabels(labels)
+		webgame.Spec.GameType = gameType
+		return webgame
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newWebGame("arcade", "webgame-2048", "2048", map[string]string{"tier": "free"}),
+		newWebGame("arcade", "webgame-tetris", "tetris", map[string]string{"tier": "paid"}),
+		newWebGame("puzzle", "webgame-2048", "2048", nil),
+		newWebGame("puzzle", "webgame-sudoku", "sudoku", map[string]string{"tier": "free"}),
+	).Build()
+	return &Server{Cache: &fakeCache{reader: reader}}
+}
+
+func TestGames(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		code  int
+		// games are the namespace/name of the listed games
+		games []string
+		more  bool
+	}{{
+		name:  "all games",
+		query: "",
+		code:  http.StatusOK,
+		games: []string{"arcade/webgame-2048", "arcade/webgame-tetris", "puzzle/webgame-2048", "puzzle/webgame-sudoku"},
+	}, {
+		name:  "namespace",
+		query: "namespace=puzzle",
+		code:  http.StatusOK,
+		games: []string{"puzzle/webgame-2048", "puzzle/webgame-sudoku"},
+	}, {
+		name:  "game type",
+		query: "gameType=2048",
+		code:  http.StatusOK,
+		games: []string{"arcade/webgame-2048", "puzzle/webgame-2048"},
+	}, {
+		name:  "label selector",
+		query: "labelSelector=tier%3Dfree",
+		code:  http.StatusOK,
+		games: []string{"arcade/webgame-2048", "puzzle/webgame-sudoku"},
+	}, {
+		name:  "first page",
+		query: "limit=3",
+		code:  http.StatusOK,
+		games: []string{"arcade/webgame-2048", "arcade/webgame-tetris", "puzzle/webgame-2048"},
+		more:  true,
+	}, {
+		name:  "last page",
+		query: "limit=3&continue=cHV6emxlL3dlYmdhbWUtMjA0OA",
+		code:  http.StatusOK,
+		games: []string{"puzzle/webgame-sudoku"},
+	}, {
+		name:  "exact page",
+		query: "limit=4",
+		code:  http.StatusOK,
+		games: []string{"arcade/webgame-2048", "arcade/webgame-tetris", "puzzle/webgame-2048", "puzzle/webgame-sudoku"},
+	}, {
+		name:  "no game",
+		query: "gameType=pacman",
+		code:  http.StatusOK,
+		games: []string{},
+	}, {
+		name:  "bad limit",
+		query: "limit=0",
+		code:  http.StatusBadRequest,
+	}, {
+		name:  "limit not a number",
+		query: "limit=ten",
+		code:  http.StatusBadRequest,
+	}, {
+		name:  "bad continue token",
+		query: "continue=!",
+		code:  http.StatusBadRequest,
+	}, {
+		name:  "bad label selector",
+		query: "labelSelector=tier%3D%3D%3D",
+		code:  http.StatusBadRequest,
+	}}
+	handler := newServer(t).Handler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/games?"+tt.query, nil))
+			if recorder.Code != tt.code {
+				t.Fatalf("code = %d, want %d: %s", recorder.Code, tt.code, recorder.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var list GameList
+			if err := json.Unmarshal(recorder.Body.Bytes(), &list); err != nil {
+				t.Fatal(err)
+			}
+			games := []string{}
+			for _, game := range list.Items {
+				games = append(games, game.Namespace+"/"+game.Name)
+			}
+			if !reflect.DeepEqual(games, tt.games) {
+				t.Errorf("games = %v, want %v", games, tt.games)
+			}
+			if (list.Continue != "") != tt.more {
+				t.Errorf("continue = %q, want set %v", list.Continue, tt.more)
+			}
+		})
+	}
+}
+
+func TestGame(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+	}{{
+		name:   "game",
+		method: http.MethodGet,
+		path:   "/games/arcade/webgame-tetris",
+		code:   http.StatusOK,
+	}, {
+		name:   "not found",
+		method: http.MethodGet,
+		path:   "/games/arcade/webgame-sudoku",
+		code:   http.StatusNotFound,
+	}, {
+		name:   "namespace only",
+		method: http.MethodGet,
+		path:   "/games/arcade",
+		code:   http.StatusNotFound,
+	}, {
+		name:   "nested path",
+		method: http.MethodGet,
+		path:   "/games/arcade/webgame-tetris/status",
+		code:   http.StatusNotFound,
+	}, {
+		name:   "method not allowed",
+		method: http.MethodDelete,
+		path:   "/games/arcade/webgame-tetris",
+		code:   http.StatusMethodNotAllowed,
+	}, {
+		name:   "list method not allowed",
+		method: http.MethodPost,
+		path:   "/games",
+		code:   http.StatusMethodNotAllowed,
+	}}
+	handler := newServer(t).Handler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+			if recorder.Code != tt.code {
+				t.Fatalf("code = %d, want %d: %s", recorder.Code, tt.code, recorder.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var game Game
+			if err := json.Unmarshal(recorder.Body.Bytes(), &game); err != nil {
+				t.Fatal(err)
+			}
+			if game.Namespace != "arcade" || game.Name != "webgame-tetris" || game.GameType != "tetris" {
+				t.Errorf("game = %+v", game)
+			}
+		})
+	}
+}
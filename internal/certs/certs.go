@@ -0,0 +1,360 @@
+// Package certs manages the self-signed serving certificate of the webhook server,
+// so the webhooks can be deployed without cert-manager.
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+	// previousCACertKey holds the CA replaced by the last renewal of the CA, trusted with the new one
+	// until it expires, as the other replicas serve certificates signed by it until they rotate.
+	previousCACertKey = "ca.previous.crt"
+
+	caValidity     = 10 * 365 * 24 * time.Hour
+	certValidity   = 365 * 24 * time.Hour
+	renewThreshold = 30 * 24 * time.Hour
+)
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create;update
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;update;patch
+
+// Options describes where the serving certificate is stored and which objects trust it.
+type Options struct {
+	// Namespace and SecretName of the secret holding the CA and the serving certificate.
+	Namespace  string
+	SecretName string
+	// ServiceName is the webhook service, used for the certificate DNS names.
+	ServiceName string
+	// CertDir is the directory the webhook server reads tls.crt and tls.key from.
+	CertDir string
+	// MutatingWebhooks and ValidatingWebhooks are the names of the webhook configurations
+	// which get the CA bundle injected.
+	MutatingWebhooks   []string
+	ValidatingWebhooks []string
+	// CustomResourceDefinitions are the names of the CRDs whose conversion webhook gets the CA bundle injected.
+	CustomResourceDefinitions []string
+}
+
+// Ensure makes sure a valid serving certificate exists in the secret, writes it to the cert dir
+// and injects the CA bundle into the webhook configurations.
+func Ensure(ctx context.Context, c client.Client, opts Options) error {
+	var secret corev1.Secret
+	// the replicas starting or rotating together race for the secret, the losers use the certificate of the winner
+	racing := func(err error) bool { return errors.IsAlreadyExists(err) || errors.IsConflict(err) }
+	if err := retry.OnError(retry.DefaultRetry, racing, func() error {
+		return ensureSecret(ctx, c, opts, &secret)
+	}); err != nil {
+		return err
+	}
+
+	if err := write(opts.CertDir, secret.Data); err != nil {
+		return err
+	}
+	return injectCABundle(ctx, c, opts, caBundle(secret.Data))
+}
+
+// ensureSecret reads the secret into secret, and creates or renews the serving certificate it holds when
+// it is not valid. The stored CA signs the renewed certificate unless it is about to expire itself.
+func ensureSecret(ctx context.Context, c client.Client, opts Options, secret *corev1.Secret) error {
+	err := c.Get(ctx, client.ObjectKey{Namespace: opts.Namespace, Name: opts.SecretName}, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	notFound := errors.IsNotFound(err)
+	if !notFound && valid(secret.Data, opts.dnsNames()) {
+		return nil
+	}
+
+	data, err := generate(opts.dnsNames(), secret.Data)
+	if err != nil {
+		return err
+	}
+	if notFound {
+		secret.SetNamespace(opts.Namespace)
+		secret.SetName(opts.SecretName)
+		secret.Type = corev1.SecretTypeTLS
+		secret.Data = data
+		err = c.Create(ctx, secret)
+	} else {
+		secret.Data = data
+		err = c.Update(ctx, secret)
+	}
+	if err != nil {
+		return err
+	}
+	log.FromContext(ctx).WithName("certs").Info("webhook serving certificate generated", "secret", client.ObjectKeyFromObject(secret))
+	return nil
+}
+
+// caBundle returns the CA and the previous CA while it has not expired.
+func caBundle(data map[string][]byte) []byte {
+	bundle := data[caCertKey]
+	if previous, err := parseCertificate(data[previousCACertKey]); err == nil && time.Now().Before(previous.NotAfter) {
+		bundle = append(append([]byte{}, bundle...), data[previousCACertKey]...)
+	}
+	return bundle
+}
+
+func (o Options) dnsNames() []string {
+	return []string{
+		o.ServiceName,
+		fmt.Sprintf("%s.%s", o.ServiceName, o.Namespace),
+		fmt.Sprintf("%s.%s.svc", o.ServiceName, o.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", o.ServiceName, o.Namespace),
+	}
+}
+
+// valid reports whether data holds a serving certificate for dnsNames, signed by the stored CA,
+// that is not about to expire.
+func valid(data map[string][]byte, dnsNames []string) bool {
+	if _, err := tls.X509KeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]); err != nil {
+		return false
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data[caCertKey]) {
+		return false
+	}
+
+	cert, err := parseCertificate(data[corev1.TLSCertKey])
+	if err != nil {
+		return false
+	}
+
+	if time.Now().Add(renewThreshold).After(cert.NotAfter) {
+		return false
+	}
+
+	for _, name := range dnsNames {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: name, Roots: pool}); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCertificate parses the first certificate of a PEM block.
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// storedCA returns the CA of current and its key, an error when there is none or it is about to expire.
+func storedCA(current map[string][]byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+	ca, err := parseCertificate(current[caCertKey])
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().Add(renewThreshold).After(ca.NotAfter) {
+		return nil, nil, fmt.Errorf("CA expires at %s", ca.NotAfter)
+	}
+	block, _ := pem.Decode(current[caKeyKey])
+	if block == nil {
+		return nil, nil, fmt.Errorf("no CA key")
+	}
+	caKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, caKey, nil
+}
+
+// newCA creates a CA.
+func newCA(now time.Time) (*x509.Certificate, *rsa.PrivateKey, error) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{CommonName: "webgame-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, caKey, nil
+}
+
+// generate creates a serving certificate signed by the CA of current, or by a new CA when current has
+// no CA valid long enough. The replaced CA is kept as the previous CA.
+func generate(dnsNames []string, current map[string][]byte) (map[string][]byte, error) {
+	now := time.Now()
+
+	data := map[string][]byte{}
+	ca, caKey, err := storedCA(current)
+	if err == nil {
+		data[caCertKey] = current[caCertKey]
+		if previous, ok := current[previousCACertKey]; ok {
+			data[previousCACertKey] = previous
+		}
+	} else {
+		if ca, caKey, err = newCA(now); err != nil {
+			return nil, err
+		}
+		data[caCertKey] = pemEncode("CERTIFICATE", ca.Raw)
+		if _, err := parseCertificate(current[caCertKey]); err == nil {
+			data[previousCACertKey] = current[caCertKey]
+		}
+	}
+	data[caKeyKey] = pemEncode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(caKey))
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano() + 1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data[corev1.TLSCertKey] = pemEncode("CERTIFICATE", certDER)
+	data[corev1.TLSPrivateKeyKey] = pemEncode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	return data, nil
+}
+
+func pemEncode(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+// write stores the serving certificate in dir, skipping files that are up to date
+// so the certificate watcher of the webhook server is not triggered needlessly.
+func write(dir string, data map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		path := filepath.Join(dir, key)
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data[key]) {
+			continue
+		}
+		if err := os.WriteFile(path, data[key], 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectCABundle sets caBundle on every webhook of the configured webhook configurations
+// and on the conversion webhook of the configured CRDs.
+func injectCABundle(ctx context.Context, c client.Client, opts Options, caBundle []byte) error {
+	for _, name := range opts.MutatingWebhooks {
+		var config admissionregistrationv1.MutatingWebhookConfiguration
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, &config); err != nil {
+			return err
+		}
+		patch := client.MergeFrom(config.DeepCopy())
+		for i := range config.Webhooks {
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+		}
+		if err := c.Patch(ctx, &config, patch); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range opts.ValidatingWebhooks {
+		var config admissionregistrationv1.ValidatingWebhookConfiguration
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, &config); err != nil {
+			return err
+		}
+		patch := client.MergeFrom(config.DeepCopy())
+		for i := range config.Webhooks {
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+		}
+		if err := c.Patch(ctx, &config, patch); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range opts.CustomResourceDefinitions {
+		var crd apiextensionsv1.CustomResourceDefinition
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, &crd); err != nil {
+			return err
+		}
+		conversion := crd.Spec.Conversion
+		if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+			continue
+		}
+		patch := client.MergeFrom(crd.DeepCopy())
+		conversion.Webhook.ClientConfig.CABundle = caBundle
+		if err := c.Patch(ctx, &crd, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rotator re-runs Ensure periodically so the serving certificate is renewed before it expires.
+type Rotator struct {
+	Client   client.Client
+	Options  Options
+	Interval time.Duration
+}
+
+// Start implements manager.Runnable.
+func (r *Rotator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("certs")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := Ensure(ctx, r.Client, r.Options); err != nil {
+				logger.Error(err, "unable to rotate webhook serving certificate")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable,
+// every replica serves webhooks and needs the certificate on disk.
+func (r *Rotator) NeedLeaderElection() bool {
+	return false
+}
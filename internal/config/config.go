@@ -0,0 +1,196 @@
+// Package config holds the controller configuration read from the file given by --config.
+package config
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/webgamedevelop/webgame/internal/ingress"
+)
+
+// Config is the controller configuration shared by every WebGame.
+type Config struct {
+	// Sizes are the resource presets selected by spec.container.size.
+	Sizes map[string]corev1.ResourceRequirements `json:"sizes,omitempty"`
+	// TLS holds the platform TLS settings.
+	TLS TLSConfig `json:"tls,omitempty"`
+	// Routing holds the cluster-wide routing settings.
+	Routing RoutingConfig `json:"routing,omitempty"`
+	// Ingress holds the settings of the Ingress routing backend.
+	Ingress IngressConfig `json:"ingress,omitempty"`
+	// Static holds the settings of the shared nginx Deployments serving static games.
+	Static StaticConfig `json:"static,omitempty"`
+	// Idle holds the settings of the activator waking games with spec.idle.
+	Idle IdleConfig `json:"idle,omitempty"`
+	// ResyncPeriod is how often every WebGame is reconciled to refresh its status without a change, zero disables it.
+	ResyncPeriod metav1.Duration `json:"resyncPeriod,omitempty"`
+}
+
+// IdleConfig holds the settings of the activator, which receives the requests of games with spec.idle
+// and wakes them when they are scaled to zero.
+type IdleConfig struct {
+	// Activator is the Service in front of the activator of the manager pods, the routes of the games with
+	// spec.idle always point to it and it proxies their requests.
+	Activator ServiceReference `json:"activator,omitempty"`
+	// ClusterDomain is the DNS domain of the cluster, used to resolve the activator Service from the routes.
+	ClusterDomain string `json:"clusterDomain,omitempty"`
+	// WakeTimeout is how long the activator holds a request while the game starts, before it answers 503.
+	WakeTimeout metav1.Duration `json:"wakeTimeout,omitempty"`
+}
+
+// ServiceReference refers to a port of a Service.
+type ServiceReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Port      int32  `json:"port"`
+}
+
+// StaticConfig holds the settings of the shared nginx Deployments serving static games.
+type StaticConfig struct {
+	// Pool is how static games which do not select one share Deployments, Namespace or GameType.
+	Pool string `json:"pool,omitempty"`
+	// Image is the nginx image of the shared pods.
+	Image string `json:"image,omitempty"`
+	// FetchImage is the image downloading archive bundles, it needs sh, wget, tar and unzip.
+	FetchImage string `json:"fetchImage,omitempty"`
+	// Size is the resource preset of the nginx container.
+	Size string `json:"size,omitempty"`
+	// BasePort is the first port of the shared pods, every static game of a pool is served on its own port above it.
+	BasePort int32 `json:"basePort,omitempty"`
+}
+
+// IngressConfig holds the settings of the Ingress routing backend.
+type IngressConfig struct {
+	// Dialects maps ingress class names to the dialect configuring their ingresses, nginx, traefik or haproxy.
+	// Classes which are not listed use the dialect of their IngressClass controller, or DefaultDialect.
+	Dialects map[string]string `json:"dialects,omitempty"`
+	// DefaultDialect is the dialect of ingress classes with an unknown controller.
+	DefaultDialect string `json:"defaultDialect,omitempty"`
+}
+
+// RoutingConfig holds the cluster-wide routing settings.
+type RoutingConfig struct {
+	// Backend is the routing backend of WebGames which do not select one, Ingress or Gateway.
+	Backend string `json:"backend,omitempty"`
+	// Gateway is the parent gateway of WebGames routed by HTTPRoutes which do not select one.
+	Gateway *GatewayConfig `json:"gateway,omitempty"`
+	// HostTemplate is the text/template of the host of WebGames with routing mode Host,
+	// executed with the fields Name, Namespace, GameType and Domain.
+	HostTemplate string `json:"hostTemplate,omitempty"`
+}
+
+// GatewayConfig refers to a Gateway API Gateway.
+type GatewayConfig struct {
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// TLSConfig holds the platform TLS settings.
+type TLSConfig struct {
+	// WildcardSecret is the shared certificate of WebGames with tls mode Wildcard,
+	// it is copied to the WebGame namespace when it lives in another one.
+	WildcardSecret *corev1.SecretReference `json:"wildcardSecret,omitempty"`
+}
+
+// Default returns the configuration used when no file is given,
+// a file only needs to set what differs from it.
+func Default() Config {
+	return Config{
+		Routing: RoutingConfig{Backend: "Ingress", HostTemplate: "{{.Name}}.{{.GameType}}.{{.Domain}}"},
+		Ingress: IngressConfig{DefaultDialect: ingress.Nginx},
+		Static:  StaticConfig{Pool: "Namespace", Image: "nginx:1.25-alpine", FetchImage: "busybox:1.36", Size: "small", BasePort: 8100},
+		Idle: IdleConfig{
+			Activator:     ServiceReference{Namespace: "webgame-system", Name: "webgame-activator", Port: 8082},
+			ClusterDomain: "cluster.local",
+			WakeTimeout:   metav1.Duration{Duration: 2 * time.Minute},
+		},
+		ResyncPeriod: metav1.Duration{Duration: 10 * time.Minute},
+		Sizes: map[string]corev1.ResourceRequirements{
+			"small":  requirements("100m", "128Mi", "250m", "256Mi"),
+			"medium": requirements("250m", "256Mi", "500m", "512Mi"),
+			"large":  requirements("500m", "512Mi", "1", "1Gi"),
+		},
+	}
+}
+
+// Load reads the configuration file at path on top of Default.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	if cfg.Routing.Backend != "Ingress" && cfg.Routing.Backend != "Gateway" {
+		return cfg, fmt.Errorf("routing backend must be Ingress or Gateway, got %q", cfg.Routing.Backend)
+	}
+	if _, err := template.New("host").Parse(cfg.Routing.HostTemplate); err != nil {
+		return cfg, fmt.Errorf("invalid routing host template: %w", err)
+	}
+	if _, err := ingress.Lookup(cfg.Ingress.DefaultDialect); err != nil {
+		return cfg, err
+	}
+	for class, dialect := range cfg.Ingress.Dialects {
+		if _, err := ingress.Lookup(dialect); err != nil {
+			return cfg, fmt.Errorf("ingress class %s: %w", class, err)
+		}
+	}
+	if cfg.Static.Pool != "Namespace" && cfg.Static.Pool != "GameType" {
+		return cfg, fmt.Errorf("static pool must be Namespace or GameType, got %q", cfg.Static.Pool)
+	}
+	if _, err := cfg.Size(cfg.Static.Size); err != nil {
+		return cfg, fmt.Errorf("static: %w", err)
+	}
+	if cfg.Static.BasePort < 1024 || cfg.Static.BasePort > 60000 {
+		return cfg, fmt.Errorf("static base port must be between 1024 and 60000, got %d", cfg.Static.BasePort)
+	}
+	if cfg.Idle.Activator.Namespace == "" || cfg.Idle.Activator.Name == "" {
+		return cfg, fmt.Errorf("idle activator service requires a namespace and a name")
+	}
+	if cfg.Idle.Activator.Port < 1 || cfg.Idle.Activator.Port > 65535 {
+		return cfg, fmt.Errorf("idle activator port must be between 1 and 65535, got %d", cfg.Idle.Activator.Port)
+	}
+	if cfg.Idle.WakeTimeout.Duration <= 0 {
+		return cfg, fmt.Errorf("idle wake timeout must be positive, got %s", cfg.Idle.WakeTimeout.Duration)
+	}
+	if cfg.ResyncPeriod.Duration < 0 {
+		return cfg, fmt.Errorf("resync period must not be negative, got %s", cfg.ResyncPeriod.Duration)
+	}
+	return cfg, nil
+}
+
+// Size returns the resources of the named preset.
+func (c *Config) Size(name string) (corev1.ResourceRequirements, error) {
+	size, ok := c.Sizes[name]
+	if !ok {
+		return corev1.ResourceRequirements{}, fmt.Errorf("size %q is not configured", name)
+	}
+	return *size.DeepCopy(), nil
+}
+
+func requirements(requestCPU, requestMemory, limitCPU, limitMemory string) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(requestCPU),
+			corev1.ResourceMemory: resource.MustParse(requestMemory),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(limitCPU),
+			corev1.ResourceMemory: resource.MustParse(limitMemory),
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		// err is a part of the error, empty when the file is valid
+		err string
+	}{{
+		name: "empty file",
+	}, {
+		name: "valid file",
+		file: `
+routing:
+  backend: Gateway
+  gateway:
+    namespace: gateways
+    name: games
+ingress:
+  dialects:
+    public: traefik
+static:
+  pool: GameType
+  size: medium
+resyncPeriod: 0s
+`,
+	}, {
+		name: "unknown field",
+		file: "routing:\n  backends: Gateway\n",
+		err:  "unable to parse",
+	}, {
+		name: "routing backend",
+		file: "routing:\n  backend: Istio\n",
+		err:  "routing backend must be Ingress or Gateway",
+	}, {
+		name: "host template",
+		file: "routing:\n  hostTemplate: \"{{.Name\"\n",
+		err:  "invalid routing host template",
+	}, {
+		name: "default dialect",
+		file: "ingress:\n  defaultDialect: envoy\n",
+		err:  "envoy",
+	}, {
+		name: "class dialect",
+		file: "ingress:\n  dialects:\n    public: envoy\n",
+		err:  "ingress class public",
+	}, {
+		name: "static pool",
+		file: "static:\n  pool: Cluster\n",
+		err:  "static pool must be Namespace or GameType",
+	}, {
+		name: "static size",
+		file: "static:\n  size: huge\n",
+		err:  `static: size "huge" is not configured`,
+	}, {
+		name: "static base port",
+		file: "static:\n  basePort: 80\n",
+		err:  "static base port must be between 1024 and 60000",
+	}, {
+		name: "activator service",
+		file: "idle:\n  activator:\n    namespace: \"\"\n    name: activator\n    port: 8082\n",
+		err:  "idle activator service requires a namespace and a name",
+	}, {
+		name: "activator port",
+		file: "idle:\n  activator:\n    namespace: webgame-system\n    name: activator\n    port: 70000\n",
+		err:  "idle activator port must be between 1 and 65535",
+	}, {
+		name: "wake timeout",
+		file: "idle:\n  wakeTimeout: 0s\n",
+		err:  "idle wake timeout must be positive",
+	}, {
+		name: "resync period",
+		file: "resyncPeriod: -1m\n",
+		err:  "resync period must not be negative",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Load error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Routing.Backend != "Ingress" || cfg.ResyncPeriod.Duration != 10*time.Minute {
+		t.Errorf("config = %+v, want the defaults", cfg)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("static:\n  basePort: 9000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// a file only overrides the fields it sets
+	if cfg.Static.BasePort != 9000 || cfg.Static.Pool != "Namespace" || cfg.Static.Image != "nginx:1.25-alpine" {
+		t.Errorf("static = %+v", cfg.Static)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
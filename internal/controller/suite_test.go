@@ -37,6 +37,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	webgamev1 "github.com/webgamedevelop/webgame/api/v1"
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/config"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -80,6 +82,9 @@ var _ = BeforeSuite(func() {
 	err = webgamev1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = webgamev2.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	// +kubebuilder:scaffold:scheme
 	mgr, err = ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:                 scheme.Scheme,
@@ -89,8 +94,11 @@ var _ = BeforeSuite(func() {
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&WebGameReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Scheme:    mgr.GetScheme(),
+		Config:    config.Default(),
+		Recorder:  mgr.GetEventRecorderFor("webgame-controller"),
 	}).SetupWithManager(mgr)
 	Expect(err).ToNot(HaveOccurred())
 
@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/config"
+)
+
+// activatorRouteIndex indexes the WebGames with an idle policy by the key of their route.
+const activatorRouteIndex = "activator.route"
+
+const (
+	// requestFlushInterval is how often the activator writes the last request time of the games.
+	requestFlushInterval = 30 * time.Second
+	// wakePollInterval is how often the activator checks whether a waking game is available.
+	wakePollInterval    = 500 * time.Millisecond
+	activatorShutdown   = 10 * time.Second
+	activatorReadHeader = 10 * time.Second
+)
+
+// Activator receives the requests of the WebGames with an idle policy. It records when each game last received
+// a request, wakes idle games and holds their requests until a replica is available, then proxies them to the
+// game service with the route prefix stripped. Every manager replica serves the activator from its cache.
+type Activator struct {
+	// Client reads the games from the manager cache and writes their annotations.
+	Client client.Client
+	// Config holds the routing settings the routes of the games are built from, and the wake timeout.
+	Config config.Config
+	// BindAddress is the address the activator listens on.
+	BindAddress string
+
+	mu sync.Mutex
+	// requests are the last request times not written to the games yet.
+	requests map[types.NamespacedName]time.Time
+}
+
+// SetupWithManager indexes the games by route and adds the activator to the manager.
+func (a *Activator) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &webgamev2.WebGame{}, activatorRouteIndex, a.indexRoute); err != nil {
+		return err
+	}
+	return mgr.Add(a)
+}
+
+// Start implements manager.Runnable.
+func (a *Activator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("activator")
+	server := &http.Server{
+		Addr:              a.BindAddress,
+		Handler:           a,
+		ReadHeaderTimeout: activatorReadHeader,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), activatorShutdown)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "unable to shut down the activator")
+		}
+		a.flushRequests(shutdownCtx)
+	}()
+	go wait.UntilWithContext(ctx, a.flushRequests, requestFlushInterval)
+
+	logger.Info("serving activator", "address", a.BindAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica receives requests.
+func (a *Activator) NeedLeaderElection() bool {
+	return false
+}
+
+// indexRoute returns the route key of a game with an idle policy, from its effective spec once it is reconciled.
+func (a *Activator) indexRoute(obj client.Object) []string {
+	webgame, ok := obj.(*webgamev2.WebGame)
+	if !ok || webgame.Spec.Idle == nil {
+		return nil
+	}
+	effective := webgame.DeepCopy()
+	if webgame.Status.EffectiveSpec != nil {
+		effective.Spec = *webgame.Status.EffectiveSpec
+	}
+	route, err := routeOf(&a.Config.Routing, effective)
+	if err != nil {
+		return nil
+	}
+	if route.Host != "" {
+		return []string{"host:" + route.Host}
+	}
+	return []string{"path:" + route.Prefix}
+}
+
+// ServeHTTP implements http.Handler.
+func (a *Activator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	logger := log.FromContext(ctx).WithName("activator")
+
+	webgame, prefix, err := a.lookup(ctx, req)
+	if err != nil {
+		logger.Error(err, "unable to look up game", "host", req.Host, "path", req.URL.Path)
+		http.Error(w, "unable to look up game", http.StatusInternalServerError)
+		return
+	}
+	if webgame == nil {
+		http.NotFound(w, req)
+		return
+	}
+	key := client.ObjectKeyFromObject(webgame)
+	a.recordRequest(key, time.Now())
+
+	if err := a.wake(ctx, webgame); err != nil {
+		logger.Error(err, "game did not become available", "webgame", key)
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "game is starting, retry later", http.StatusServiceUnavailable)
+		return
+	}
+
+	var service corev1.Service
+	if err := a.Client.Get(ctx, key, &service); err != nil || len(service.Spec.Ports) == 0 {
+		logger.Error(err, "unable to get game service", "webgame", key)
+		http.Error(w, "game service not found", http.StatusBadGateway)
+		return
+	}
+	target := &url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(service.Spec.ClusterIP, strconv.Itoa(int(service.Spec.Ports[0].Port))),
+	}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(r.In.URL.Path, prefix), "/")
+			r.Out.URL.RawPath = ""
+			r.Out.Host = r.In.Host
+			r.SetXForwarded()
+		},
+	}
+	proxy.ServeHTTP(w, req)
+}
+
+// lookup returns the game a request is for, by its host first and then by the path prefix of the game,
+// with the prefix to strip. The game is nil when no game with an idle policy matches.
+func (a *Activator) lookup(ctx context.Context, req *http.Request) (*webgamev2.WebGame, string, error) {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	webgame, err := a.find(ctx, "host:"+strings.ToLower(host))
+	if webgame != nil || err != nil {
+		return webgame, "", err
+	}
+
+	// path routes are /{gameType}/{name}
+	segments := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 3)
+	if len(segments) < 2 {
+		return nil, "", nil
+	}
+	prefix := "/" + segments[0] + "/" + segments[1]
+	webgame, err = a.find(ctx, "path:"+prefix)
+	return webgame, prefix, err
+}
+
+// find returns the game with the route key, nil when there is none.
+func (a *Activator) find(ctx context.Context, key string) (*webgamev2.WebGame, error) {
+	var webgames webgamev2.WebGameList
+	if err := a.Client.List(ctx, &webgames, client.MatchingFields{activatorRouteIndex: key}); err != nil {
+		return nil, err
+	}
+	if len(webgames.Items) == 0 {
+		return nil, nil
+	}
+	return &webgames.Items[0], nil
+}
+
+// recordRequest remembers the time of a request, written to the game by the next flush.
+func (a *Activator) recordRequest(key types.NamespacedName, t time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.requests == nil {
+		a.requests = map[types.NamespacedName]time.Time{}
+	}
+	if t.After(a.requests[key]) {
+		a.requests[key] = t
+	}
+}
+
+// flushRequests writes the last request times to the annotation of the games, the controller reads it to
+// decide when a game goes idle. Times older than the annotation, written by another replica, are skipped.
+func (a *Activator) flushRequests(ctx context.Context) {
+	a.mu.Lock()
+	requests := a.requests
+	a.requests = nil
+	a.mu.Unlock()
+
+	for key, t := range requests {
+		var webgame webgamev2.WebGame
+		if err := a.Client.Get(ctx, key, &webgame); err != nil {
+			continue
+		}
+		if last, ok := annotationTime(&webgame, lastRequestAnnotation); ok && !t.Truncate(time.Second).After(last) {
+			continue
+		}
+		if err := a.annotate(ctx, &webgame, lastRequestAnnotation, t); err != nil && !apierrors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, "unable to record the last request", "webgame", key)
+			a.recordRequest(key, t)
+		}
+	}
+}
+
+// wake waits until the game deployment has an available replica. While the game is idle, it sets the wake
+// annotation, so the controller scales the game up, until the controller has seen it.
+func (a *Activator) wake(ctx context.Context, webgame *webgamev2.WebGame) error {
+	key := client.ObjectKeyFromObject(webgame)
+	ctx, cancel := context.WithTimeout(ctx, a.Config.Idle.WakeTimeout.Duration)
+	defer cancel()
+
+	start := time.Now()
+	woken := false
+	err := wait.PollUntilContextCancel(ctx, wakePollInterval, true, func(ctx context.Context) (bool, error) {
+		var current webgamev2.WebGame
+		if err := a.Client.Get(ctx, key, &current); err != nil {
+			return false, err
+		}
+		if isIdle(&current.Status) {
+			if wake, ok := annotationTime(&current, wakeAnnotation); !ok || !wake.After(current.Status.Idle.LastTransitionTime.Time) {
+				woken = true
+				return false, a.annotate(ctx, &current, wakeAnnotation, time.Now())
+			}
+			return false, nil
+		}
+
+		var deployment appsv1.Deployment
+		if err := a.Client.Get(ctx, key, &deployment); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return deployment.Status.AvailableReplicas > 0, nil
+	})
+	if err != nil {
+		return err
+	}
+	if woken {
+		wakeDuration.WithLabelValues(webgame.GetNamespace(), webgame.Spec.GameType).Observe(time.Since(start).Seconds())
+	}
+	return nil
+}
+
+// annotate sets the annotation key of the webgame to t with a merge patch, so it does not conflict with the controller.
+func (a *Activator) annotate(ctx context.Context, webgame *webgamev2.WebGame, key string, t time.Time) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, key, t.UTC().Format(time.RFC3339))
+	return a.Client.Patch(ctx, webgame, client.RawPatch(types.MergePatchType, []byte(patch)))
+}
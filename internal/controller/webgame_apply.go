@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+const (
+	// fieldManager is the field manager the controller applies the Deployments, Services and Ingresses of the games with.
+	fieldManager = "webgame-controller"
+	// legacyFieldManager is the field manager of the children the controller updated before it applied them,
+	// the default field manager of the manager binary. Its fields are handed over to fieldManager.
+	legacyFieldManager = "manager"
+)
+
+// Condition reasons of the Drifted condition.
+const (
+	ReasonDriftDetected = "DriftDetected"
+	ReasonNoDrift       = "NoDrift"
+)
+
+// Event reasons of the drift of the children.
+const (
+	EventReasonDriftDetected  = "DriftDetected"
+	EventReasonDriftCorrected = "DriftCorrected"
+)
+
+// ownerReference returns the controller reference of the children applied for webgame.
+func ownerReference(webgame *webgamev2.WebGame) *metav1ac.OwnerReferenceApplyConfiguration {
+	return metav1ac.OwnerReference().
+		WithAPIVersion(webgamev2.GroupVersion.String()).
+		WithKind("WebGame").
+		WithName(webgame.GetName()).
+		WithUID(webgame.GetUID()).
+		WithController(true).
+		WithBlockOwnerDeletion(true)
+}
+
+// applyChild applies the apply configuration of a child of the webgame with the field manager of the controller,
+// and reads the child into obj. A field of the configuration changed by another manager conflicts with the
+// controller: with the Correct drift policy the field is taken back, with ReportOnly the child is left as it is
+// and the field is added to status.drift.
+func (r *WebGameReconciler) applyChild(ctx context.Context, webgame *webgamev2.WebGame, obj client.Object, configuration interface{}, status *webgamev2.WebGameStatus) (controllerutil.OperationResult, error) {
+	data, err := json.Marshal(configuration)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	desired := &unstructured.Unstructured{}
+	if err := desired.UnmarshalJSON(data); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	res := controllerutil.OperationResultUpdated
+	if err := r.Get(ctx, client.ObjectKeyFromObject(desired), obj); errors.IsNotFound(err) {
+		res = controllerutil.OperationResultCreated
+	} else if err != nil {
+		return controllerutil.OperationResultNone, err
+	} else if err := r.upgradeManagedFields(ctx, obj); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	previous := obj.GetResourceVersion()
+
+	applied := desired.DeepCopy()
+	err = r.Patch(ctx, applied, client.Apply, client.FieldOwner(fieldManager))
+	drift := driftOf(err, desired)
+	switch {
+	case len(drift) == 0 && err != nil:
+		return controllerutil.OperationResultNone, err
+	case len(drift) != 0 && webgame.Spec.DriftPolicy == webgamev2.DriftPolicyReportOnly:
+		status.Drift = append(status.Drift, drift...)
+		return controllerutil.OperationResultNone, nil
+	case len(drift) != 0:
+		applied = desired.DeepCopy()
+		if err := r.Patch(ctx, applied, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		log.FromContext(ctx).Info("drift corrected", "kind", desired.GetKind(), "name", desired.GetName(), "fields", len(drift))
+		r.Recorder.Eventf(webgame, corev1.EventTypeWarning, EventReasonDriftCorrected, "%s %s: %s", desired.GetKind(), desired.GetName(), driftMessage(drift))
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(applied.Object, obj); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if res == controllerutil.OperationResultUpdated && obj.GetResourceVersion() == previous {
+		res = controllerutil.OperationResultNone
+	}
+	return res, nil
+}
+
+// upgradeManagedFields hands the fields of a child the controller updated before it applied them over to the
+// field manager of the controller, so they do not conflict with it.
+func (r *WebGameReconciler) upgradeManagedFields(ctx context.Context, obj client.Object) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(obj, sets.New(legacyFieldManager), fieldManager)
+	if err != nil || patch == nil {
+		return err
+	}
+	return r.Patch(ctx, obj, client.RawPatch(types.JSONPatchType, patch))
+}
+
+// driftOf returns the fields of the apply conflict err, nil when err is not a conflict.
+func driftOf(err error, desired *unstructured.Unstructured) []webgamev2.DriftedField {
+	if !errors.IsConflict(err) {
+		return nil
+	}
+	status, ok := err.(errors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return nil
+	}
+	var drift []webgamev2.DriftedField
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		// the message is: conflict with "manager" using apps/v1
+		manager := cause.Message
+		if _, err := fmt.Sscanf(cause.Message, "conflict with %q", &manager); err != nil {
+			manager = strings.TrimPrefix(manager, "conflict with ")
+		}
+		drift = append(drift, webgamev2.DriftedField{
+			Kind:    desired.GetKind(),
+			Name:    desired.GetName(),
+			Field:   cause.Field,
+			Manager: manager,
+		})
+	}
+	return drift
+}
+
+// driftMessage lists the drifted fields with the managers which changed them.
+func driftMessage(drift []webgamev2.DriftedField) string {
+	fields := make([]string, 0, len(drift))
+	for _, d := range drift {
+		fields = append(fields, fmt.Sprintf("%s %s %s changed by %s", d.Kind, d.Name, d.Field, d.Manager))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// setDriftCondition sets the Drifted condition from the fields in status.drift.
+func setDriftCondition(status *webgamev2.WebGameStatus, generation int64) {
+	if len(status.Drift) == 0 {
+		setCondition(status, generation, webgamev2.ConditionDrifted, metav1.ConditionFalse, ReasonNoDrift, "child resources match the applied fields")
+		return
+	}
+	setCondition(status, generation, webgamev2.ConditionDrifted, metav1.ConditionTrue, ReasonDriftDetected, driftMessage(status.Drift))
+}
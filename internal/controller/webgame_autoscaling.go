@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+
+// desiredReplicas returns the replicas written to the game deployment, zero while the game is idle. It is nil
+// when the game is autoscaled, the replicas are left to the autoscaler alone: the applied deployment has no
+// replicas, a new or woken one starts with the API server default of one replica and is scaled to the minimum
+// of the autoscaler.
+func desiredReplicas(webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) *int32 {
+	if isIdle(status) {
+		var replicas int32
+		return &replicas
+	}
+	if webgame.Spec.Scaling.Autoscaling != nil {
+		return nil
+	}
+	return webgame.Spec.Scaling.Replicas
+}
+
+// desiredMinReplicas returns the minimum replicas of the autoscaler, 1 when unset like the API server default.
+func desiredMinReplicas(autoscaling *webgamev2.AutoscalingSpec) *int32 {
+	if autoscaling.MinReplicas != nil {
+		return autoscaling.MinReplicas
+	}
+	var replicas int32 = 1
+	return &replicas
+}
+
+// reconcileAutoscaler creates or updates the autoscaler of the game deployment,
+// or deletes it when autoscaling is disabled.
+func (r *WebGameReconciler) reconcileAutoscaler(ctx context.Context, webgame *webgamev2.WebGame, deployment *appsv1.Deployment) error {
+	autoscaling := webgame.Spec.Scaling.Autoscaling
+	if autoscaling == nil {
+		return r.deleteChild(ctx, webgame, &autoscalingv2.HorizontalPodAutoscaler{})
+	}
+
+	var hpa autoscalingv2.HorizontalPodAutoscaler
+	hpa.SetNamespace(webgame.GetNamespace())
+	hpa.SetName(webgame.GetName())
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &hpa, func() error {
+		hpa.SetLabels(labels.Merge(hpa.GetLabels(), webgame.GetLabels()))
+		hpa.Spec.ScaleTargetRef = autoscalingv2.CrossVersionObjectReference{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+			Name:       deployment.GetName(),
+		}
+		hpa.Spec.MinReplicas = desiredMinReplicas(autoscaling)
+		hpa.Spec.MaxReplicas = autoscaling.MaxReplicas
+		hpa.Spec.Metrics = autoscalingMetrics(autoscaling)
+		return controllerutil.SetControllerReference(webgame, &hpa, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("horizontalpodautoscaler changed", "res", res)
+		r.recordChildChange(webgame, &hpa, res)
+	}
+	return nil
+}
+
+// defaultCPUUtilization is the target the API server defaults an autoscaler without metrics to,
+// set explicitly so the autoscaler is not updated on every reconcile.
+const defaultCPUUtilization int32 = 80
+
+// autoscalingMetrics returns the metrics of the autoscaler.
+func autoscalingMetrics(autoscaling *webgamev2.AutoscalingSpec) []autoscalingv2.MetricSpec {
+	var metrics []autoscalingv2.MetricSpec
+	for _, target := range []struct {
+		name        corev1.ResourceName
+		utilization *int32
+	}{
+		{corev1.ResourceCPU, autoscaling.TargetCPUUtilization},
+		{corev1.ResourceMemory, autoscaling.TargetMemoryUtilization},
+	} {
+		if target.utilization == nil {
+			continue
+		}
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: target.name,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: target.utilization,
+				},
+			},
+		})
+	}
+	metrics = append(metrics, autoscaling.Metrics...)
+	if len(metrics) == 0 {
+		utilization := defaultCPUUtilization
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+			},
+		})
+	}
+	return metrics
+}
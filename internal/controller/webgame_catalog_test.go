@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/catalog"
+)
+
+var _ = Describe("Test game catalog", func() {
+	const (
+		timeout   = time.Second * 10
+		interval  = time.Millisecond * 250
+		namespace = "webgames-catalog"
+	)
+
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		var ns corev1.Namespace
+		ns.SetName(namespace)
+		_, err := controllerutil.CreateOrUpdate(ctx, k8sClient, &ns, func() error { return nil })
+		Expect(err).Should(Succeed())
+
+		server = httptest.NewServer((&catalog.Server{Cache: mgr.GetCache()}).Handler())
+		DeferCleanup(server.Close)
+	})
+
+	createWebGame := func(name, gameType string) *webgamev2.WebGame {
+		var replicas int32 = 1
+		webgame := &webgamev2.WebGame{}
+		webgame.SetNamespace(namespace)
+		webgame.SetName(name)
+		webgame.SetLabels(map[string]string{"portal": "public"})
+		webgame.Spec.DisplayName = name
+		webgame.Spec.GameType = gameType
+		webgame.Spec.Container.Image = "webgamedevelop/" + gameType + ":latest"
+		webgame.Spec.Routing.Domain = "localhost"
+		webgame.Spec.Scaling.Replicas = &replicas
+		Expect(k8sClient.Create(ctx, webgame)).Should(Succeed())
+		return webgame
+	}
+
+	getList := func(query string) catalog.GameList {
+		var list catalog.GameList
+		resp, err := http.Get(server.URL + "/games?" + query)
+		Expect(err).Should(Succeed())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+		Expect(json.NewDecoder(resp.Body).Decode(&list)).Should(Succeed())
+		return list
+	}
+
+	It("list, get and watch the games", func() {
+		first := createWebGame("catalog-first", "mario")
+		createWebGame("catalog-second", "mario")
+		createWebGame("catalog-third", "tetris")
+
+		// the list is paginated and filtered
+		Eventually(func() int {
+			return len(getList("namespace=" + namespace + "&gameType=mario").Items)
+		}, timeout, interval).Should(Equal(2))
+		page := getList("namespace=" + namespace + "&gameType=mario&labelSelector=portal%3Dpublic&limit=1")
+		Expect(page.Items).Should(HaveLen(1))
+		Expect(page.Items[0].Name).Should(Equal("catalog-first"))
+		Expect(page.Continue).ShouldNot(BeEmpty())
+		page = getList("namespace=" + namespace + "&gameType=mario&limit=1&continue=" + page.Continue)
+		Expect(page.Items).Should(HaveLen(1))
+		Expect(page.Items[0].Name).Should(Equal("catalog-second"))
+		Expect(page.Continue).Should(BeEmpty())
+		Expect(getList("namespace=" + namespace + "&labelSelector=portal%3Dprivate").Items).Should(BeEmpty())
+
+		// a single game is read by namespace and name
+		resp, err := http.Get(server.URL + "/games/" + namespace + "/catalog-first")
+		Expect(err).Should(Succeed())
+		var game catalog.Game
+		Expect(json.NewDecoder(resp.Body).Decode(&game)).Should(Succeed())
+		Expect(resp.Body.Close()).Should(Succeed())
+		Expect(game.DisplayName).Should(Equal("catalog-first"))
+		Expect(game.GameType).Should(Equal("mario"))
+		resp, err = http.Get(server.URL + "/games/" + namespace + "/catalog-missing")
+		Expect(err).Should(Succeed())
+		Expect(resp.Body.Close()).Should(Succeed())
+		Expect(resp.StatusCode).Should(Equal(http.StatusNotFound))
+
+		// the stream starts with the current games, then reports their changes
+		resp, err = http.Get(server.URL + "/games?watch=true&namespace=" + namespace + "&gameType=tetris")
+		Expect(err).Should(Succeed())
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("Content-Type")).Should(Equal("text/event-stream"))
+		events := make(chan string, 100)
+		go func() {
+			defer GinkgoRecover()
+			defer close(events)
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if line, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+					scanner.Scan()
+					events <- line + " " + strings.TrimPrefix(scanner.Text(), "data: ")
+				}
+			}
+		}()
+		Eventually(events, timeout).Should(Receive(And(HavePrefix(catalog.EventAdded), ContainSubstring(`"catalog-third"`))))
+
+		createWebGame("catalog-fourth", "tetris")
+		Eventually(events, timeout).Should(Receive(And(HavePrefix(catalog.EventAdded), ContainSubstring(`"catalog-fourth"`))))
+
+		Expect(k8sClient.Delete(ctx, first)).Should(Succeed())
+		Consistently(events, time.Second).ShouldNot(Receive(ContainSubstring(`"catalog-first"`)))
+	})
+})
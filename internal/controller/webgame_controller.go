@@ -3,27 +3,47 @@ package controller
 import (
 	"context"
 	"fmt"
-	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	appsv1ac "k8s.io/client-go/applyconfigurations/apps/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
-	webgamev1 "github.com/webgamedevelop/webgame/api/v1"
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/config"
 )
 
 // WebGameReconciler reconciles a WebGame object
 type WebGameReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Config   config.Config
+	Recorder record.EventRecorder
+	// APIReader reads the secrets, whose metadata only is cached.
+	APIReader client.Reader
+
+	readyTimer readyTimer
 }
 
 // +kubebuilder:rbac:groups=webgame.webgame.tech,resources=webgames,verbs=get;list;watch;create;update;patch;delete
@@ -41,12 +61,12 @@ type WebGameReconciler struct {
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *WebGameReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *WebGameReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
 	logger.V(2).Info("webgame event received")
 	defer func() { logger.V(2).Info("webgame event handling completed") }()
 
-	var webgame webgamev1.WebGame
+	var webgame webgamev2.WebGame
 	if err := r.Get(ctx, req.NamespacedName, &webgame); err != nil {
 		if errors.IsNotFound(err) {
 			logger.Info("webgame not found. Ignoring since object must be deleted")
@@ -57,157 +77,380 @@ func (r *WebGameReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
+	if !webgame.GetDeletionTimestamp().IsZero() {
+		r.readyTimer.forget(req.NamespacedName)
+		return r.finalize(ctx, &webgame)
+	}
+
+	if controllerutil.AddFinalizer(&webgame, webgameFinalizer) {
+		if err := r.Update(ctx, &webgame); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if updated, err := r.rollbackTo(ctx, &webgame); updated || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// conditions are collected on a copy and written on every return path
+	status := webgame.Status.DeepCopy()
+	if webgame.GetGeneration() != webgame.Status.ObservedGeneration {
+		r.readyTimer.specChanged(req.NamespacedName, time.Now())
+	}
+	defer func() {
+		setDriftCondition(status, webgame.GetGeneration())
+		summarize(status, webgame.GetGeneration(), reterr)
+		if meta.IsStatusConditionTrue(status.Conditions, webgamev2.ConditionReady) {
+			r.readyTimer.ready(&webgame, time.Now())
+		}
+		r.recordTransitions(&webgame, &webgame.Status, status)
+		if err := r.syncStatus(ctx, &webgame, status); err != nil {
+			logger.Error(err, "unable to sync webgame status")
+			if reterr == nil {
+				reterr = err
+			}
+		}
+	}()
+
+	// from here on webgame.Spec is the effective spec
+	cfg, requeue, err := r.resolveSpec(ctx, &webgame, status)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if requeue != 0 {
+		return ctrl.Result{RequeueAfter: requeue}, nil
+	}
+	scheduleRequeue, err := r.applySchedules(&webgame, status)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// the children report their drift again
+	status.Drift = nil
+
+	if staticSource(&webgame) != nil {
+		result, err := r.reconcileStatic(ctx, &webgame, status)
+		result.RequeueAfter = minRequeue(result.RequeueAfter, scheduleRequeue, r.Config.ResyncPeriod.Duration)
+		return result, err
+	}
 	selector := map[string]string{
 		"gameType": webgame.Spec.GameType,
 		"instance": webgame.GetName(),
 	}
 
-	// create deployment
-	var deployment = appsv1.Deployment{}
-	deployment.SetNamespace(webgame.GetNamespace())
-	deployment.SetName(webgame.GetName())
-	mutate := func() error {
-		deployment.SetLabels(labels.Merge(deployment.GetLabels(), webgame.GetLabels()))
-		deployment.Spec.Replicas = webgame.Spec.Replicas
-		deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
-		deployment.Spec.Template.SetLabels(labels.Merge(webgame.GetLabels(), selector))
+	// every child is converged in one pass, the children depending on one which failed are skipped
+	// and the errors are returned together
+	var errs []error
+	idleRequeue := r.reconcileIdle(&webgame, status)
+	deployment, resources, err := r.reconcileDeployment(ctx, &webgame, &cfg, selector, status)
+	if err != nil {
+		errs = append(errs, err)
+	}
 
-		container := corev1.Container{}
-		if len(deployment.Spec.Template.Spec.Containers) != 0 {
-			container = deployment.Spec.Template.Spec.Containers[0]
+	var probeRequeue, rolloutRequeue time.Duration
+	if deployment != nil {
+		if err := r.reconcileAutoscaler(ctx, &webgame, deployment); err != nil {
+			errs = append(errs, err)
 		}
-		container.Name = webgame.GetName()
-		container.Image = webgame.Spec.Image
-		container.ImagePullPolicy = corev1.PullIfNotPresent
-		container.Resources = corev1.ResourceRequirements{}
-		container.Ports = []corev1.ContainerPort{{
-			Name:          "web",
-			ContainerPort: int32(webgame.Spec.ServerPort.IntValue()),
-			Protocol:      corev1.ProtocolTCP,
-		}}
+		if err := r.reconcileDisruptionBudget(ctx, &webgame, deployment, selector, status); err != nil {
+			errs = append(errs, err)
+		}
+		if probeRequeue, err = r.checkProbes(ctx, &webgame, &deployment.Spec.Template.Spec.Containers[0], selector, status); err != nil {
+			errs = append(errs, err)
+		}
+		// a rollback changes the spec, the reconcile it triggers deploys it
+		rolledBack, err := r.autoRollback(ctx, &webgame, status)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if rolledBack {
+			return ctrl.Result{}, kerrors.NewAggregate(errs)
+		}
+	}
+
+	service, err := r.reconcileService(ctx, &webgame, serviceSelector(selector, status), status)
+	if err != nil {
+		errs = append(errs, err)
+	} else if err := r.finishReplacement(ctx, &webgame, service, selector, status); err != nil {
+		errs = append(errs, err)
+	} else if err := r.leaveStaticPool(ctx, &webgame, status.Static); err != nil {
+		// a game which was static leaves its pool once the service selects the game pods
+		errs = append(errs, err)
+	} else {
+		status.Static = nil
+	}
+
+	if deployment != nil {
+		if rolloutRequeue, err = r.reconcileCanary(ctx, &webgame, deployment, resources, status); err != nil {
+			errs = append(errs, err)
+		}
+	}
 
-		deployment.Spec.Template.Spec.Containers = []corev1.Container{container}
-		return ctrl.SetControllerReference(&webgame, &deployment, r.Scheme)
+	if service != nil {
+		// games with an idle policy are routed through the activator, awake or idle
+		routeService, err := r.reconcileActivatorService(ctx, &webgame, service)
+		if err != nil {
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionServiceReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+			errs = append(errs, err)
+		} else if err := r.reconcileRoute(ctx, &webgame, routeService, status); err != nil {
+			errs = append(errs, err)
+		} else if !rolloutActive(status.Rollout) {
+			// the canary is removed once the routes no longer send it requests
+			if err := r.deleteCanary(ctx, &webgame); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
 
-	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &deployment, mutate)
+	if len(errs) != 0 {
+		return ctrl.Result{}, kerrors.NewAggregate(errs)
+	}
+	return ctrl.Result{RequeueAfter: minRequeue(probeRequeue, rolloutRequeue, idleRequeue, scheduleRequeue, r.Config.ResyncPeriod.Duration)}, nil
+}
+
+// reconcileDeployment creates or updates the game deployment and sets the DeploymentReady condition. It returns
+// the deployment and the resources of the game container, the deployment is nil when it could not be reconciled
+// or is being replaced.
+func (r *WebGameReconciler) reconcileDeployment(ctx context.Context, webgame *webgamev2.WebGame, cfg *config.Config, selector map[string]string, status *webgamev2.WebGameStatus) (*appsv1.Deployment, corev1.ResourceRequirements, error) {
+	logger := log.FromContext(ctx)
+	resources, err := resolveResources(cfg, &webgame.Spec.Container)
 	if err != nil {
-		return ctrl.Result{}, err
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionDeploymentReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return nil, resources, err
 	}
+	status.Resources = &resources
 
-	if res != controllerutil.OperationResultNone {
-		logger.Info("deployment changed", "res", res)
-		return ctrl.Result{}, nil
-	}
-
-	// create service
-	var service = corev1.Service{}
-	service.SetNamespace(webgame.GetNamespace())
-	service.SetName(webgame.GetName())
-	mutate = func() error {
-		service.SetLabels(labels.Merge(service.GetLabels(), webgame.GetLabels()))
-		service.Spec.Selector = selector
-		service.Spec.Type = corev1.ServiceTypeClusterIP
-		service.Spec.Ports = []corev1.ServicePort{{
-			Name:       "web",
-			Port:       int32(webgame.Spec.ServerPort.IntValue()),
-			TargetPort: webgame.Spec.ServerPort,
-			Protocol:   corev1.ProtocolTCP,
-		}}
-		return controllerutil.SetControllerReference(&webgame, &service, r.Scheme)
-	}
-
-	res, err = ctrl.CreateOrUpdate(ctx, r.Client, &service, mutate)
+	restart, err := r.checkImagePullSecrets(ctx, webgame, status)
 	if err != nil {
-		return ctrl.Result{}, err
+		return nil, resources, err
 	}
 
-	if res != controllerutil.OperationResultNone {
-		logger.Info("service changed", "res", res)
-		return ctrl.Result{}, nil
-	}
-
-	// create ingress
-	var (
-		ingress     = networkingv1.Ingress{}
-		pathType    = networkingv1.PathTypePrefix
-		path        = fmt.Sprintf("/%s/%s", selector["gameType"], selector["instance"])
-		rewriteRule = fmt.Sprintf(`rewrite ^%s/(.*)$ /$1 break;`, path)
-		annotations = map[string]string{
-			"nginx.ingress.kubernetes.io/configuration-snippet": rewriteRule,
-		}
-	)
-
-	ingress.SetNamespace(webgame.GetNamespace())
-	ingress.SetName(webgame.GetName())
-	mutate = func() error {
-		ingress.SetLabels(labels.Merge(ingress.GetLabels(), webgame.GetLabels()))
-		ingress.SetAnnotations(labels.Merge(ingress.GetAnnotations(), annotations))
-		ingress.Spec = networkingv1.IngressSpec{
-			IngressClassName: &webgame.Spec.IngressClass,
-			Rules: []networkingv1.IngressRule{{
-				IngressRuleValue: networkingv1.IngressRuleValue{
-					HTTP: &networkingv1.HTTPIngressRuleValue{
-						Paths: []networkingv1.HTTPIngressPath{{
-							PathType: &pathType,
-							Path:     path,
-							Backend: networkingv1.IngressBackend{
-								Service: &networkingv1.IngressServiceBackend{
-									Name: service.GetName(),
-									Port: networkingv1.ServiceBackendPort{
-										Number: int32(webgame.Spec.ServerPort.IntValue()),
-									},
-								},
-							},
-						}},
-					},
-				},
-			}},
-		}
+	// the stable image stays deployed while a canary runs
+	image, err := r.stableImage(ctx, webgame, status)
+	if err != nil {
+		return nil, resources, err
+	}
 
-		return controllerutil.SetControllerReference(&webgame, &ingress, r.Scheme)
+	var deployment appsv1.Deployment
+	if err := r.Get(ctx, client.ObjectKeyFromObject(webgame), &deployment); client.IgnoreNotFound(err) != nil {
+		return nil, resources, err
+	}
+	if selectorChanged(&deployment, selector) {
+		return nil, resources, r.replaceDeployment(ctx, webgame, &deployment, selector, status)
+	}
+	configuration := gameDeployment(webgame, webgame.GetName(), selector, desiredReplicas(webgame, status), image, resources)
+	// the restart time is applied again, or the pods would restart when it is removed
+	restartedAt := deployment.Spec.Template.GetAnnotations()[restartedAtAnnotation]
+	if restart {
+		restartedAt = time.Now().Format(time.RFC3339)
+	}
+	if restartedAt != "" {
+		configuration.Spec.Template.WithAnnotations(map[string]string{restartedAtAnnotation: restartedAt})
 	}
 
-	res, err = ctrl.CreateOrUpdate(ctx, r.Client, &ingress, mutate)
+	start := time.Now()
+	res, err := r.applyChild(ctx, webgame, &deployment, configuration, status)
+	observeStep(stepDeployment, start)
 	if err != nil {
-		return ctrl.Result{}, err
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionDeploymentReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return nil, resources, err
 	}
 
+	status.DeploymentStatus = *deployment.Status.DeepCopy()
+	status.Replicas = deployment.Status.Replicas
+	status.Selector = labels.SelectorFromSet(selector).String()
+	recordRevision(webgame, image, status)
+
+	if restart {
+		logger.Info("image pull secrets found, pods restarted")
+	}
 	if res != controllerutil.OperationResultNone {
-		logger.Info("ingress changed", "res", res)
-		return ctrl.Result{}, nil
+		logger.Info("deployment changed", "res", res)
+		r.recordChildChange(webgame, &deployment, res)
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionDeploymentReady, metav1.ConditionFalse, ReasonRollingOut, fmt.Sprintf("deployment %s", res))
+		return &deployment, resources, nil
 	}
+	conditionStatus, reason, message := deploymentCondition(&deployment)
+	setCondition(status, webgame.GetGeneration(), webgamev2.ConditionDeploymentReady, conditionStatus, reason, message)
+	return &deployment, resources, nil
+}
 
-	logger.Info("sync status")
-	mutate = func() error {
-		index := strings.TrimPrefix(webgame.Spec.IndexPage, "/")
-		path := strings.TrimPrefix(path, "/")
-		address := fmt.Sprintf("%s/%s/%s", webgame.Spec.Domain, path, index)
-		webgame.Status.DeploymentStatus = *deployment.Status.DeepCopy()
-		webgame.Status.GameAddress = address
-		webgame.Status.ClusterIP = service.Spec.ClusterIP
-		return nil
+// reconcileService creates or updates the game service and sets the ServiceReady condition,
+// the service is nil when it could not be reconciled.
+func (r *WebGameReconciler) reconcileService(ctx context.Context, webgame *webgamev2.WebGame, selector map[string]string, status *webgamev2.WebGameStatus) (*corev1.Service, error) {
+	var service corev1.Service
+	start := time.Now()
+	res, err := r.applyChild(ctx, webgame, &service, gameService(webgame, webgame.GetName(), selector, webgame.Spec.Networking.ServerPort), status)
+	observeStep(stepService, start)
+	if err != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionServiceReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return nil, err
 	}
 
-	// update webgame status
-	res, err = controllerutil.CreateOrPatch(ctx, r.Client, &webgame, mutate)
+	status.ClusterIP = service.Spec.ClusterIP
+	setCondition(status, webgame.GetGeneration(), webgamev2.ConditionServiceReady, metav1.ConditionTrue, ReasonSynced, fmt.Sprintf("service %s", res))
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("service changed", "res", res)
+		r.recordChildChange(webgame, &service, res)
+	}
+	return &service, nil
+}
+
+// reconcileRoute creates or updates the route of the game to service, through an ingress or a gateway, deletes
+// the route of the backend used before, and sets the game address.
+func (r *WebGameReconciler) reconcileRoute(ctx context.Context, webgame *webgamev2.WebGame, service *corev1.Service, status *webgamev2.WebGameStatus) error {
+	tlsSecret, err := r.tlsSecretName(ctx, webgame, status)
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
 	}
 
-	if res != controllerutil.OperationResultNone {
-		// non-return
-		logger.Info("webgame status synced")
+	// create the route to the game, through an ingress or a gateway
+	backend := r.routingBackend(webgame)
+	route, err := routeOf(&r.Config.Routing, webgame)
+	if err != nil {
+		conditionType := webgamev2.ConditionIngressReady
+		if backend == webgamev2.RoutingBackendGateway {
+			conditionType = webgamev2.ConditionHTTPRouteReady
+		}
+		setCondition(status, webgame.GetGeneration(), conditionType, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return err
+	}
+	// the activator strips the prefix itself, it tells the games apart by their prefix
+	route.KeepPrefix = webgame.Spec.Idle != nil
+
+	// the ingress terminates TLS with the secret, a gateway with the certificate of its listener
+	var https bool
+	switch backend {
+	case webgamev2.RoutingBackendGateway:
+		// the route condition tells which backend was used before
+		if meta.FindStatusCondition(status.Conditions, webgamev2.ConditionIngressReady) != nil {
+			if err := r.deleteChild(ctx, webgame, &networkingv1.Ingress{}); err != nil {
+				return err
+			}
+			meta.RemoveStatusCondition(&status.Conditions, webgamev2.ConditionIngressReady)
+		}
+		start := time.Now()
+		_, err = r.reconcileHTTPRoute(ctx, webgame, service, route, status)
+		observeStep(stepHTTPRoute, start)
+		if err == nil {
+			https, err = r.gatewayServesHTTPS(ctx, webgame)
+		}
+	default:
+		if meta.FindStatusCondition(status.Conditions, webgamev2.ConditionHTTPRouteReady) != nil {
+			if err := r.deleteChild(ctx, webgame, newHTTPRoute()); err != nil {
+				return err
+			}
+			meta.RemoveStatusCondition(&status.Conditions, webgamev2.ConditionHTTPRouteReady)
+		}
+		start := time.Now()
+		_, err = r.reconcileIngress(ctx, webgame, service, route, tlsSecret, status)
+		observeStep(stepIngress, start)
+		https = tlsSecret != ""
+	}
+	if err != nil {
+		return err
+	}
+
+	status.GameAddress = route.gameAddress(webgame, https)
+	return nil
+}
+
+// gameDeployment returns the apply configuration of a game deployment named name running image,
+// with the pods selected by selector. The replicas are left out when nil.
+func gameDeployment(webgame *webgamev2.WebGame, name string, selector map[string]string, replicas *int32, image string, resources corev1.ResourceRequirements) *appsv1ac.DeploymentApplyConfiguration {
+	container := corev1ac.Container().
+		WithName(webgame.GetName()).
+		WithImage(image).
+		WithImagePullPolicy(corev1.PullIfNotPresent).
+		WithResources(resourcesApplyConfiguration(resources)).
+		WithPorts(corev1ac.ContainerPort().
+			WithName("web").
+			WithContainerPort(webgame.Spec.Networking.ServerPort).
+			WithProtocol(corev1.ProtocolTCP))
+	setProbes(webgame, container)
+
+	podSpec := corev1ac.PodSpec().WithContainers(container)
+	for _, secret := range webgame.Spec.Container.ImagePullSecrets {
+		podSpec.WithImagePullSecrets(corev1ac.LocalObjectReference().WithName(secret.Name))
+	}
+	spec := appsv1ac.DeploymentSpec().
+		WithSelector(metav1ac.LabelSelector().WithMatchLabels(selector)).
+		WithTemplate(corev1ac.PodTemplateSpec().
+			WithLabels(labels.Merge(webgame.GetLabels(), selector)).
+			WithSpec(podSpec))
+	if replicas != nil {
+		spec.WithReplicas(*replicas)
 	}
-	return ctrl.Result{}, nil
+	return appsv1ac.Deployment(name, webgame.GetNamespace()).
+		WithLabels(webgame.GetLabels()).
+		WithOwnerReferences(ownerReference(webgame)).
+		WithSpec(spec)
+}
+
+// resourcesApplyConfiguration returns the apply configuration of the requests and limits which are set.
+func resourcesApplyConfiguration(resources corev1.ResourceRequirements) *corev1ac.ResourceRequirementsApplyConfiguration {
+	configuration := corev1ac.ResourceRequirements()
+	if len(resources.Requests) != 0 {
+		configuration.WithRequests(resources.Requests)
+	}
+	if len(resources.Limits) != 0 {
+		configuration.WithLimits(resources.Limits)
+	}
+	return configuration
+}
+
+// gameService returns the apply configuration of a game service named name, selecting the pods of selector.
+// The service port is the server port.
+func gameService(webgame *webgamev2.WebGame, name string, selector map[string]string, targetPort int32) *corev1ac.ServiceApplyConfiguration {
+	return corev1ac.Service(name, webgame.GetNamespace()).
+		WithLabels(webgame.GetLabels()).
+		WithOwnerReferences(ownerReference(webgame)).
+		WithSpec(corev1ac.ServiceSpec().
+			WithSelector(selector).
+			WithType(corev1.ServiceTypeClusterIP).
+			WithPorts(corev1ac.ServicePort().
+				WithName("web").
+				WithPort(webgame.Spec.Networking.ServerPort).
+				WithTargetPort(intstr.FromInt(int(targetPort))).
+				WithProtocol(corev1.ProtocolTCP)))
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *WebGameReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&webgamev1.WebGame{}).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &webgamev2.WebGame{}, imagePullSecretsIndex, indexImagePullSecrets); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &webgamev2.WebGame{}, tlsSecretIndex, r.indexTLSSecret); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &webgamev2.WebGame{}, gameTypeIndex, indexGameType); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &webgamev2.WebGame{}, staticBundleIndex, indexStaticBundle); err != nil {
+		return err
+	}
+	if err := metrics.Registry.Register(&fleetCollector{reader: mgr.GetCache()}); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&webgamev2.WebGame{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
 		Owns(&networkingv1.Ingress{}).
-		Complete(r)
+		Owns(&batchv1.Job{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretToWebGames), builder.OnlyMetadata).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(podToWebGame)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.bundleToWebGames)).
+		Watches(&appsv1.Deployment{}, handler.EnqueueRequestsFromMapFunc(staticPoolToWebGames)).
+		Watches(&webgamev2.GameTemplate{}, handler.EnqueueRequestsFromMapFunc(r.templateToWebGames))
+
+	// HTTPRoutes are only watched when the Gateway API is installed
+	if _, err := mgr.GetRESTMapper().RESTMapping(httpRouteGVK.GroupKind(), httpRouteGVK.Version); err == nil {
+		b = b.Owns(newHTTPRoute())
+	} else if !meta.IsNoMatchError(err) {
+		return err
+	}
+	return b.Complete(r)
 }
@@ -1,19 +1,27 @@
 package controller
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
-	webgamev1 "github.com/webgamedevelop/webgame/api/v1"
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
 )
 
 var _ = Describe("Test Webgame controller", func() {
@@ -31,23 +39,49 @@ var _ = Describe("Test Webgame controller", func() {
 		Expect(err).Should(Succeed())
 	})
 
+	// newWebGame returns a game of the test namespace running the 2048 image with one replica, for a test to
+	// change before creating it with createWebGame.
+	newWebGame := func(name string) *webgamev2.WebGame {
+		var replicas int32 = 1
+		webgame := &webgamev2.WebGame{}
+		webgame.SetNamespace(namespace)
+		webgame.SetName(name)
+		webgame.Spec = webgamev2.WebGameSpec{
+			DisplayName: "test-" + name,
+			GameType:    "2048",
+			Container:   webgamev2.ContainerSpec{Image: "webgamedevelop/2048:latest"},
+			Networking:  webgamev2.NetworkingSpec{ServerPort: 80, IngressClass: "nginx"},
+			Routing:     webgamev2.RoutingSpec{Domain: "localhost", IndexPage: "/index.html"},
+			Scaling:     webgamev2.ScalingSpec{Replicas: &replicas},
+		}
+		return webgame
+	}
+
+	// createWebGame creates the game and deletes it once the test ends.
+	createWebGame := func(webgame *webgamev2.WebGame) {
+		Expect(k8sClient.Create(ctx, webgame)).Should(Succeed())
+		DeferCleanup(func() {
+			Expect(ctrlclient.IgnoreNotFound(k8sClient.Delete(ctx, webgame))).Should(Succeed())
+		})
+	}
+
 	Context("webgame controller test", func() {
 		It("create webgame sample", func() {
 			var err error
 			var replicas int32 = 1
-			var webgame webgamev1.WebGame
+			var webgame webgamev2.WebGame
 			webgame.SetNamespace(namespace)
 			webgame.SetName(webgameInstanceName)
 			mutate := func() error {
 				webgame.Spec.DisplayName = "test-webgame-instance"
 				webgame.Spec.GameType = "2048"
-				webgame.Spec.IngressClass = "nginx"
-				webgame.Spec.Domain = "localhost"
-				webgame.Spec.IndexPage = "index.html"
-				webgame.Spec.ServerPort = intstr.IntOrString{Type: intstr.Int, IntVal: int32(80)}
-				webgame.Spec.Image = "webgamedevelop/2048:latest"
-				webgame.Spec.Replicas = &replicas
-				webgame.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "test-image-pull-secret"}}
+				webgame.Spec.Networking.IngressClass = "nginx"
+				webgame.Spec.Routing.Domain = "localhost"
+				webgame.Spec.Routing.IndexPage = "index.html"
+				webgame.Spec.Networking.ServerPort = 80
+				webgame.Spec.Container.Image = "webgamedevelop/2048:latest"
+				webgame.Spec.Scaling.Replicas = &replicas
+				webgame.Spec.Container.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "test-image-pull-secret"}}
 				return nil
 			}
 
@@ -86,11 +120,28 @@ var _ = Describe("Test Webgame controller", func() {
 				}
 				return true
 			}, timeout, interval).Should(BeTrue())
+
+			// the nginx dialect rewrites without snippets
+			var ingress networkingv1.Ingress
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(&webgame), &ingress)).Should(Succeed())
+			Expect(ingress.GetAnnotations()).Should(HaveKeyWithValue("nginx.ingress.kubernetes.io/rewrite-target", "/$2"))
+			Expect(ingress.GetAnnotations()).ShouldNot(HaveKey("nginx.ingress.kubernetes.io/configuration-snippet"))
+
+			// get status conditions
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(&webgame), &webgame); err != nil {
+					return false
+				}
+				return webgame.Status.ObservedGeneration == webgame.GetGeneration() &&
+					meta.IsStatusConditionTrue(webgame.Status.Conditions, webgamev2.ConditionIngressReady)
+			}, timeout, interval).Should(BeTrue())
+			Expect(webgame.Status.Phase).ShouldNot(BeEmpty())
+			Expect(meta.FindStatusCondition(webgame.Status.Conditions, webgamev2.ConditionReady)).ShouldNot(BeNil())
 		})
 
 		It("delete webgame instance", func() {
 			var err error
-			var webgame webgamev1.WebGame
+			var webgame webgamev2.WebGame
 			webgame.SetNamespace(namespace)
 			webgame.SetName(webgameInstanceName)
 			err = k8sClient.Delete(ctx, &webgame)
@@ -105,5 +156,731 @@ var _ = Describe("Test Webgame controller", func() {
 				return false
 			}, timeout, interval).Should(BeTrue())
 		})
+
+		It("delete webgame instance with retain policy", func() {
+			webgame := newWebGame("webgame-retain")
+			webgame.Spec.DeletionPolicy = webgamev2.DeletionPolicyRetain
+			createWebGame(webgame)
+			// the retained children outlive the webgame
+			for _, child := range []ctrlclient.Object{&appsv1.Deployment{}, &corev1.Service{}, &networkingv1.Ingress{}} {
+				child.SetNamespace(webgame.GetNamespace())
+				child.SetName(webgame.GetName())
+				DeferCleanup(func(child ctrlclient.Object) {
+					Expect(ctrlclient.IgnoreNotFound(k8sClient.Delete(ctx, child))).Should(Succeed())
+				}, child)
+			}
+
+			// wait until every child is created
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &networkingv1.Ingress{}); err != nil {
+					return false
+				}
+				return true
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, webgame)).Should(Succeed())
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame)
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			// children are kept without owner references
+			var deployment appsv1.Deployment
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)).Should(Succeed())
+			Expect(deployment.GetOwnerReferences()).Should(BeEmpty())
+		})
+
+		It("resolve size preset and resource overrides", func() {
+			webgame := newWebGame("webgame-sized")
+			webgame.Spec.Container.Size = webgamev2.SizeSmall
+			webgame.Spec.Container.Resources = corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+			}
+			createWebGame(webgame)
+
+			var deployment appsv1.Deployment
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)
+			}, timeout, interval).Should(Succeed())
+			limits := deployment.Spec.Template.Spec.Containers[0].Resources.Limits
+			Expect(limits.Cpu().String()).Should(Equal("250m"))
+			Expect(limits.Memory().String()).Should(Equal("512Mi"))
+
+			// resolved resources are reported in status
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return false
+				}
+				return webgame.Status.Resources != nil && webgame.Status.Resources.Requests.Cpu().String() == "100m"
+			}, timeout, interval).Should(BeTrue())
+		})
+
+		It("restart pods when a missing image pull secret appears", func() {
+			webgame := newWebGame("webgame-private")
+			webgame.Spec.Container.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "private-registry"}}
+			createWebGame(webgame)
+
+			// the secret is missing
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return ""
+				}
+				condition := meta.FindStatusCondition(webgame.Status.Conditions, webgamev2.ConditionImagePullSecretsReady)
+				if condition == nil {
+					return ""
+				}
+				return condition.Reason
+			}, timeout, interval).Should(Equal(ReasonSecretNotFound))
+
+			var deployment appsv1.Deployment
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)).Should(Succeed())
+			Expect(deployment.Spec.Template.Spec.ImagePullSecrets).Should(Equal(webgame.Spec.Container.ImagePullSecrets))
+
+			// the secret appears
+			var secret corev1.Secret
+			secret.SetNamespace(namespace)
+			secret.SetName("private-registry")
+			secret.Type = corev1.SecretTypeDockerConfigJson
+			secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)}
+			Expect(k8sClient.Create(ctx, &secret)).Should(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, &secret)
+
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment); err != nil {
+					return false
+				}
+				_, ok := deployment.Spec.Template.GetAnnotations()[restartedAtAnnotation]
+				return ok
+			}, timeout, interval).Should(BeTrue())
+		})
+
+		It("serve the game over https once the certificate is ready", func() {
+			webgame := newWebGame("webgame-tls")
+			webgame.Spec.Routing.Domain = "games.example.com"
+			webgame.Spec.Routing.TLS = &webgamev2.TLSSpec{
+				Mode:       webgamev2.TLSModeSecret,
+				SecretName: "games-tls",
+				HSTS:       &webgamev2.HSTSSpec{MaxAge: 600},
+			}
+			createWebGame(webgame)
+
+			// the certificate secret is missing
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return ""
+				}
+				condition := meta.FindStatusCondition(webgame.Status.Conditions, webgamev2.ConditionCertificateReady)
+				if condition == nil {
+					return ""
+				}
+				return condition.Reason
+			}, timeout, interval).Should(Equal(ReasonCertificateNotReady))
+
+			var secret corev1.Secret
+			secret.SetNamespace(namespace)
+			secret.SetName("games-tls")
+			secret.Type = corev1.SecretTypeTLS
+			secret.Data = map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")}
+			Expect(k8sClient.Create(ctx, &secret)).Should(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, &secret)
+
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return false
+				}
+				return meta.IsStatusConditionTrue(webgame.Status.Conditions, webgamev2.ConditionCertificateReady) &&
+					strings.HasPrefix(webgame.Status.GameAddress, "https://")
+			}, timeout, interval).Should(BeTrue())
+
+			var ingress networkingv1.Ingress
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &ingress)).Should(Succeed())
+			Expect(ingress.Spec.TLS).Should(HaveLen(1))
+			Expect(ingress.Spec.TLS[0].SecretName).Should(Equal("games-tls"))
+			Expect(ingress.GetAnnotations()).Should(HaveKeyWithValue("nginx.ingress.kubernetes.io/force-ssl-redirect", "true"))
+
+			// the nginx dialect has no per-ingress HSTS
+			Expect(ingress.GetAnnotations()).ShouldNot(HaveKey("nginx.ingress.kubernetes.io/configuration-snippet"))
+			condition := meta.FindStatusCondition(webgame.Status.Conditions, webgamev2.ConditionIngressReady)
+			Expect(condition).ShouldNot(BeNil())
+			Expect(condition.Reason).Should(Equal(ReasonFeatureUnsupported))
+			Expect(condition.Message).Should(ContainSubstring("HSTS"))
+		})
+
+		It("configure the ingress with the dialect of the ingress class", func() {
+			var ingressClass networkingv1.IngressClass
+			ingressClass.SetName("haproxy")
+			ingressClass.Spec.Controller = "haproxy.org/ingress-controller/haproxy"
+			Expect(k8sClient.Create(ctx, &ingressClass)).Should(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, &ingressClass)
+
+			webgame := newWebGame("webgame-haproxy")
+			webgame.Spec.Networking.IngressClass = "haproxy"
+			createWebGame(webgame)
+
+			var ingress networkingv1.Ingress
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &ingress)
+			}, timeout, interval).Should(Succeed())
+			Expect(ingress.GetAnnotations()).Should(HaveKeyWithValue(dialectAnnotation, "haproxy"))
+			Expect(ingress.GetAnnotations()).Should(HaveKey("haproxy.org/path-rewrite"))
+			Expect(ingress.GetAnnotations()).ShouldNot(HaveKey("nginx.ingress.kubernetes.io/rewrite-target"))
+		})
+
+		It("serve the game on its own host in host routing mode", func() {
+			webgame := newWebGame("webgame-host")
+			webgame.Spec.Routing.Mode = webgamev2.RoutingModeHost
+			webgame.Spec.Routing.Domain = "games.example.com"
+			createWebGame(webgame)
+
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return ""
+				}
+				return webgame.Status.GameAddress
+			}, timeout, interval).Should(Equal("http://webgame-host.2048.games.example.com/index.html"))
+
+			var ingress networkingv1.Ingress
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &ingress)).Should(Succeed())
+			Expect(ingress.Spec.Rules[0].Host).Should(Equal("webgame-host.2048.games.example.com"))
+			Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Path).Should(Equal("/"))
+			Expect(ingress.GetAnnotations()).ShouldNot(HaveKey("nginx.ingress.kubernetes.io/rewrite-target"))
+		})
+
+		It("scale the game with a horizontal pod autoscaler", func() {
+			var minReplicas int32 = 2
+			webgame := newWebGame("webgame-autoscaled")
+			webgame.Spec.Scaling.Autoscaling = &webgamev2.AutoscalingSpec{MinReplicas: &minReplicas, MaxReplicas: 5}
+			createWebGame(webgame)
+
+			var hpa autoscalingv2.HorizontalPodAutoscaler
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &hpa)
+			}, timeout, interval).Should(Succeed())
+			Expect(hpa.Spec.MaxReplicas).Should(Equal(int32(5)))
+			Expect(hpa.Spec.ScaleTargetRef.Name).Should(Equal(webgame.GetName()))
+
+			// the replicas are left to the autoscaler, not set from spec.scaling.replicas
+			var deployment appsv1.Deployment
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)).Should(Succeed())
+			Expect(*deployment.Spec.Replicas).Should(Equal(int32(1)))
+
+			// the replicas set by the autoscaler survive a reconcile
+			scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 4}}
+			Expect(k8sClient.SubResource("scale").Update(ctx, &deployment, ctrlclient.WithSubResourceBody(scale))).Should(Succeed())
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame)).Should(Succeed())
+			webgame.Spec.DisplayName = "test-webgame-autoscaled-renamed"
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return false
+				}
+				return webgame.Status.ObservedGeneration == webgame.GetGeneration()
+			}, timeout, interval).Should(BeTrue())
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)).Should(Succeed())
+			Expect(*deployment.Spec.Replicas).Should(Equal(int32(4)))
+
+			// the scale subresource reads the selector from status
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return ""
+				}
+				return webgame.Status.Selector
+			}, timeout, interval).Should(Equal("gameType=2048,instance=webgame-autoscaled"))
+		})
+
+		It("probe the index page of the game", func() {
+			webgame := newWebGame("webgame-probes")
+			webgame.Spec.Container.Probes = &webgamev2.ProbesSpec{
+				Liveness: &corev1.Probe{FailureThreshold: 5},
+				Startup: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{
+					TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(80)},
+				}},
+			}
+			createWebGame(webgame)
+
+			var deployment appsv1.Deployment
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)
+			}, timeout, interval).Should(Succeed())
+			container := deployment.Spec.Template.Spec.Containers[0]
+
+			// the default probe gets the index page through the named port
+			Expect(container.ReadinessProbe.HTTPGet).ShouldNot(BeNil())
+			Expect(container.ReadinessProbe.HTTPGet.Path).Should(Equal("/index.html"))
+			Expect(container.ReadinessProbe.HTTPGet.Port).Should(Equal(intstr.FromString("web")))
+
+			// an override without a handler keeps the default one
+			Expect(container.LivenessProbe.HTTPGet).ShouldNot(BeNil())
+			Expect(container.LivenessProbe.FailureThreshold).Should(Equal(int32(5)))
+
+			Expect(container.StartupProbe.HTTPGet).Should(BeNil())
+			Expect(container.StartupProbe.TCPSocket).ShouldNot(BeNil())
+
+			// the deployment is not updated again once the API server defaulted the probes
+			generation := deployment.GetGeneration()
+			Consistently(func() int64 {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment); err != nil {
+					return 0
+				}
+				return deployment.GetGeneration()
+			}, time.Second, interval).Should(Equal(generation))
+		})
+
+		It("roll a new image out to a canary", func() {
+			webgame := newWebGame("webgame-canary")
+			webgame.Spec.Container.Image = "webgamedevelop/2048:v1"
+			webgame.Spec.Rollout = webgamev2.RolloutSpec{Canary: &webgamev2.CanaryStrategy{
+				Steps: []webgamev2.CanaryStep{{Weight: 20}, {Weight: 50}},
+			}}
+			createWebGame(webgame)
+
+			// the first image is deployed without canary
+			var deployment appsv1.Deployment
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)
+			}, timeout, interval).Should(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).Should(Equal("webgamedevelop/2048:v1"))
+
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame)).Should(Succeed())
+			webgame.Spec.Container.Image = "webgamedevelop/2048:v2"
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+
+			// the new image runs in the canary deployment, behind a canary ingress
+			canaryKey := ctrlclient.ObjectKey{Namespace: namespace, Name: "webgame-canary-canary"}
+			var canary appsv1.Deployment
+			Eventually(func() error {
+				return k8sClient.Get(ctx, canaryKey, &canary)
+			}, timeout, interval).Should(Succeed())
+			Expect(canary.Spec.Template.Spec.Containers[0].Image).Should(Equal("webgamedevelop/2048:v2"))
+			Expect(canary.Spec.Selector.MatchLabels).Should(HaveKeyWithValue("instance", "webgame-canary-canary"))
+
+			var canaryIngress networkingv1.Ingress
+			Eventually(func() error {
+				return k8sClient.Get(ctx, canaryKey, &canaryIngress)
+			}, timeout, interval).Should(Succeed())
+			Expect(canaryIngress.GetAnnotations()).Should(HaveKeyWithValue("nginx.ingress.kubernetes.io/canary", "true"))
+			Expect(canaryIngress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).Should(Equal("webgame-canary-canary"))
+
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)).Should(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).Should(Equal("webgamedevelop/2048:v1"))
+
+			// aborting removes the canary and keeps the stable image
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame)).Should(Succeed())
+			webgame.Spec.Rollout.Canary.Abort = true
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+			Eventually(func() webgamev2.RolloutPhase {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil || webgame.Status.Rollout == nil {
+					return ""
+				}
+				return webgame.Status.Rollout.Phase
+			}, timeout, interval).Should(Equal(webgamev2.RolloutAborted))
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, canaryKey, &canaryIngress))
+			}, timeout, interval).Should(BeTrue())
+			Expect(meta.IsStatusConditionFalse(webgame.Status.Conditions, webgamev2.ConditionRolloutComplete)).Should(BeTrue())
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)).Should(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).Should(Equal("webgamedevelop/2048:v1"))
+		})
+
+		It("keep the revision history and roll back to a revision", func() {
+			webgame := newWebGame("webgame-history")
+			webgame.Spec.Container.Image = "webgamedevelop/2048:v1"
+			createWebGame(webgame)
+
+			historyImages := func() []string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return nil
+				}
+				var images []string
+				for _, revision := range webgame.Status.History {
+					images = append(images, fmt.Sprintf("%d:%s", revision.Revision, revision.Image))
+				}
+				return images
+			}
+			Eventually(historyImages, timeout, interval).Should(Equal([]string{"1:webgamedevelop/2048:v1"}))
+
+			webgame.Spec.Container.Image = "webgamedevelop/2048:v2"
+			webgame.Spec.Networking.ServerPort = 8080
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+			Eventually(historyImages, timeout, interval).Should(Equal([]string{"1:webgamedevelop/2048:v1", "2:webgamedevelop/2048:v2"}))
+
+			// the revision rolled back to becomes the newest one
+			var revision int64 = 1
+			webgame.Spec.RollbackTo = &revision
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+			Eventually(historyImages, timeout, interval).Should(Equal([]string{"2:webgamedevelop/2048:v2", "3:webgamedevelop/2048:v1"}))
+			Expect(webgame.Spec.RollbackTo).Should(BeNil())
+			Expect(webgame.Spec.Container.Image).Should(Equal("webgamedevelop/2048:v1"))
+			Expect(webgame.Spec.Networking.ServerPort).Should(Equal(int32(80)))
+		})
+
+		It("merge the spec over the game type template", func() {
+			var maxInstances int32 = 1
+			var template webgamev2.GameTemplate
+			template.SetName("tetris")
+			template.Spec = webgamev2.GameTemplateSpec{
+				Defaults: webgamev2.GameDefaults{
+					Image:      "webgamedevelop/tetris:v1",
+					ServerPort: 8080,
+					IndexPage:  "/play.html",
+				},
+				MaxInstancesPerNamespace: &maxInstances,
+			}
+			Expect(k8sClient.Create(ctx, &template)).Should(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, &template)
+
+			// the image, the port and the index page come from the template
+			newTetrisGame := func(name string) *webgamev2.WebGame {
+				webgame := newWebGame(name)
+				webgame.Spec.GameType = "tetris"
+				webgame.Spec.Container = webgamev2.ContainerSpec{}
+				webgame.Spec.Networking = webgamev2.NetworkingSpec{}
+				webgame.Spec.Routing.IndexPage = ""
+				return webgame
+			}
+			webgame := newTetrisGame("webgame-template")
+			createWebGame(webgame)
+
+			var deployment appsv1.Deployment
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)
+			}, timeout, interval).Should(Succeed())
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).Should(Equal("webgamedevelop/tetris:v1"))
+			Expect(container.Ports[0].ContainerPort).Should(Equal(int32(8080)))
+			Expect(container.ReadinessProbe.HTTPGet.Path).Should(Equal("/play.html"))
+
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil || webgame.Status.EffectiveSpec == nil {
+					return ""
+				}
+				return webgame.Status.EffectiveSpec.Container.Image
+			}, timeout, interval).Should(Equal("webgamedevelop/tetris:v1"))
+			Expect(webgame.Status.Template).Should(Equal("tetris"))
+			Expect(webgame.Spec.Container.Image).Should(BeEmpty())
+
+			// a template change is applied to the games of the type
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(&template), &template)).Should(Succeed())
+			template.Spec.Defaults.Image = "webgamedevelop/tetris:v2"
+			Expect(k8sClient.Update(ctx, &template)).Should(Succeed())
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment); err != nil {
+					return ""
+				}
+				return deployment.Spec.Template.Spec.Containers[0].Image
+			}, timeout, interval).Should(Equal("webgamedevelop/tetris:v2"))
+
+			// the second game of the namespace is over the instance limit
+			second := newTetrisGame("webgame-template-second")
+			createWebGame(second)
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(second), second); err != nil {
+					return ""
+				}
+				condition := meta.FindStatusCondition(second.Status.Conditions, webgamev2.ConditionSpecResolved)
+				if condition == nil {
+					return ""
+				}
+				return condition.Reason
+			}, timeout, interval).Should(Equal(ReasonInstanceLimitExceeded))
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(second), &deployment))).Should(BeTrue())
+		})
+
+		It("serve static games from a shared nginx deployment", func() {
+			var bundle corev1.ConfigMap
+			bundle.SetNamespace(namespace)
+			bundle.SetName("static-bundle")
+			bundle.Data = map[string]string{"index.html": "<html></html>"}
+			Expect(k8sClient.Create(ctx, &bundle)).Should(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, &bundle)
+
+			var replicas int32 = 2
+			newStaticGame := func(name string, source webgamev2.StaticSource) *webgamev2.WebGame {
+				webgame := newWebGame(name)
+				webgame.Spec.GameType = "puzzle"
+				webgame.Spec.Source = &webgamev2.SourceSpec{Static: &source}
+				webgame.Spec.Container = webgamev2.ContainerSpec{}
+				webgame.Spec.Scaling.Replicas = &replicas
+				createWebGame(webgame)
+				return webgame
+			}
+			first := newStaticGame("webgame-static-first", webgamev2.StaticSource{ConfigMap: &corev1.LocalObjectReference{Name: "static-bundle"}})
+			second := newStaticGame("webgame-static-second", webgamev2.StaticSource{ArchiveURL: "https://games.example.com/second.zip"})
+
+			// both games are served by the pool of the namespace, on their own port
+			var pool appsv1.Deployment
+			Eventually(func() int {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: "webgame-static"}, &pool); err != nil {
+					return 0
+				}
+				return len(pool.Spec.Template.Spec.Volumes)
+			}, timeout, interval).Should(Equal(3))
+			Expect(*pool.Spec.Replicas).Should(Equal(int32(2)))
+			Expect(pool.Spec.Template.Spec.InitContainers).Should(HaveLen(1))
+			Expect(pool.Spec.Template.Spec.InitContainers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "WEBGAME_BUNDLE_URL", Value: "https://games.example.com/second.zip"}))
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(first), &appsv1.Deployment{}))).Should(BeTrue())
+
+			var service corev1.Service
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(first), &service)
+			}, timeout, interval).Should(Succeed())
+			Expect(service.Spec.Selector).Should(Equal(map[string]string{"webgame.webgame.tech/static-pool": "webgame-static"}))
+			Eventually(func() *webgamev2.StaticStatus {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(first), first); err != nil {
+					return nil
+				}
+				return first.Status.Static
+			}, timeout, interval).ShouldNot(BeNil())
+			Expect(service.Spec.Ports[0].TargetPort.IntValue()).Should(Equal(int(first.Status.Static.Port)))
+			Expect(meta.IsStatusConditionTrue(first.Status.Conditions, webgamev2.ConditionBundleReady)).Should(BeTrue())
+
+			var conf corev1.ConfigMap
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: "webgame-static"}, &conf)).Should(Succeed())
+			Expect(conf.Data["default.conf"]).Should(ContainSubstring(fmt.Sprintf("listen %d;", first.Status.Static.Port)))
+
+			// a deleted game leaves the pool
+			Expect(k8sClient.Delete(ctx, second)).Should(Succeed())
+			Eventually(func() int {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(&pool), &pool); err != nil {
+					return 0
+				}
+				return len(pool.Spec.Template.Spec.Volumes)
+			}, timeout, interval).Should(Equal(2))
+			Expect(pool.Spec.Template.Spec.InitContainers).Should(BeEmpty())
+		})
+
+		It("protect a game with several replicas with a pod disruption budget", func() {
+			var replicas int32 = 3
+			webgame := newWebGame("webgame-disruption")
+			webgame.Spec.Scaling.Replicas = &replicas
+			createWebGame(webgame)
+
+			// maxUnavailable 1 by default
+			var pdb policyv1.PodDisruptionBudget
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &pdb)
+			}, timeout, interval).Should(Succeed())
+			Expect(pdb.Spec.MaxUnavailable).ShouldNot(BeNil())
+			Expect(pdb.Spec.MaxUnavailable.IntValue()).Should(Equal(1))
+			Expect(pdb.Spec.MinAvailable).Should(BeNil())
+			Expect(pdb.Spec.Selector.MatchLabels).Should(Equal(map[string]string{"gameType": "2048", "instance": "webgame-disruption"}))
+
+			// the budget of the spec replaces the default
+			minAvailable := intstr.FromString("50%")
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame)).Should(Succeed())
+			webgame.Spec.Disruption = &webgamev2.DisruptionSpec{MinAvailable: &minAvailable}
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+			Eventually(func() *intstr.IntOrString {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &pdb); err != nil {
+					return nil
+				}
+				return pdb.Spec.MinAvailable
+			}, timeout, interval).Should(Equal(&minAvailable))
+			Expect(pdb.Spec.MaxUnavailable).Should(BeNil())
+
+			// a single replica has no budget
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame)).Should(Succeed())
+			replicas = 1
+			webgame.Spec.Scaling.Replicas = &replicas
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &pdb))
+			}, timeout, interval).Should(BeTrue())
+		})
+
+		It("scale an idle game to zero and wake it on request", func() {
+			webgame := newWebGame("webgame-idle")
+			webgame.Spec.Idle = &webgamev2.IdleSpec{Timeout: metav1.Duration{Duration: time.Minute}}
+			createWebGame(webgame)
+
+			// the route goes through the activator, with the prefix kept for it
+			var ingress networkingv1.Ingress
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &ingress)
+			}, timeout, interval).Should(Succeed())
+			path := ingress.Spec.Rules[0].HTTP.Paths[0]
+			Expect(path.Path).Should(Equal("/2048/webgame-idle"))
+			Expect(path.Backend.Service.Name).Should(Equal("webgame-idle-activator"))
+			Expect(path.Backend.Service.Port.Number).Should(Equal(int32(8082)))
+			Expect(ingress.GetAnnotations()).ShouldNot(HaveKey("nginx.ingress.kubernetes.io/rewrite-target"))
+
+			var activator corev1.Service
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: "webgame-idle-activator"}, &activator)).Should(Succeed())
+			Expect(activator.Spec.Type).Should(Equal(corev1.ServiceTypeExternalName))
+			Expect(activator.Spec.ExternalName).Should(Equal("webgame-activator.webgame-system.svc.cluster.local"))
+
+			// the game goes idle once the timeout has passed without request
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return err
+				}
+				if webgame.Status.Idle == nil {
+					return fmt.Errorf("idle status not reported yet")
+				}
+				webgame.Status.Idle.LastTransitionTime = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+				return k8sClient.Status().Update(ctx, webgame)
+			}, timeout, interval).Should(Succeed())
+			var deployment appsv1.Deployment
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment); err != nil {
+					return -1
+				}
+				return *deployment.Spec.Replicas
+			}, timeout, interval).Should(Equal(int32(0)))
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame)).Should(Succeed())
+			Expect(webgame.Status.Idle.Phase).Should(Equal(webgamev2.IdlePhaseIdle))
+
+			// a request received by the activator wakes it
+			webgame.SetAnnotations(map[string]string{wakeAnnotation: time.Now().Add(2 * time.Second).UTC().Format(time.RFC3339)})
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment); err != nil {
+					return -1
+				}
+				return *deployment.Spec.Replicas
+			}, timeout, interval).Should(Equal(int32(1)))
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame)).Should(Succeed())
+			Expect(webgame.Status.Idle.Phase).Should(Equal(webgamev2.IdlePhaseAwake))
+		})
+
+		It("scale the game to the replicas of the active schedule", func() {
+			webgame := newWebGame("webgame-schedule")
+			webgame.Spec.Schedules = []webgamev2.ScheduleSpec{
+				{Name: "tournament", Schedule: "* * * * *", TimeZone: "Europe/Paris", Replicas: 3},
+				{Name: "leap-day", Schedule: "0 0 29 2 *", Replicas: 0},
+			}
+			createWebGame(webgame)
+
+			var deployment appsv1.Deployment
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment); err != nil {
+					return -1
+				}
+				return *deployment.Spec.Replicas
+			}, timeout, interval).Should(Equal(int32(3)))
+			Eventually(func() *webgamev2.ScheduleStatus {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return nil
+				}
+				return webgame.Status.Schedule
+			}, timeout, interval).ShouldNot(BeNil())
+			Expect(webgame.Status.Schedule.Active).Should(Equal("tournament"))
+			Expect(webgame.Status.Schedule.NextSchedule).Should(Equal("tournament"))
+			Expect(webgame.Status.Schedule.NextTransitionTime.Time).Should(BeTemporally("<=", time.Now().Add(time.Minute)))
+
+			// spec.scaling applies again without schedules
+			webgame.Spec.Schedules = nil
+			Expect(k8sClient.Update(ctx, webgame)).Should(Succeed())
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment); err != nil {
+					return -1
+				}
+				return *deployment.Spec.Replicas
+			}, timeout, interval).Should(Equal(int32(1)))
+		})
+
+		It("converge every child and the status in a single pass", func() {
+			webgame := newWebGame("webgame-single-pass")
+			createWebGame(webgame)
+
+			// no deployment controller runs, the address is set while the deployment is rolling out
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return ""
+				}
+				return webgame.Status.GameAddress
+			}, timeout, interval).Should(Equal("http://localhost/2048/webgame-single-pass/index.html"))
+			Expect(meta.IsStatusConditionTrue(webgame.Status.Conditions, webgamev2.ConditionServiceReady)).Should(BeTrue())
+			Expect(meta.IsStatusConditionTrue(webgame.Status.Conditions, webgamev2.ConditionIngressReady)).Should(BeTrue())
+			deploymentReady := meta.FindStatusCondition(webgame.Status.Conditions, webgamev2.ConditionDeploymentReady)
+			Expect(deploymentReady.Reason).Should(Equal(ReasonRollingOut))
+		})
+
+		It("report or correct the drift of the deployment", func() {
+			editImage := func(name string) {
+				var deployment appsv1.Deployment
+				Eventually(func() error {
+					if err := k8sClient.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: name}, &deployment); err != nil {
+						return err
+					}
+					deployment.Spec.Template.Spec.Containers[0].Image = "webgamedevelop/2048:edited"
+					return k8sClient.Update(ctx, &deployment, ctrlclient.FieldOwner("kubectl-edit"))
+				}, timeout, interval).Should(Succeed())
+			}
+			image := func(name string) string {
+				var deployment appsv1.Deployment
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: name}, &deployment); err != nil {
+					return ""
+				}
+				return deployment.Spec.Template.Spec.Containers[0].Image
+			}
+
+			for _, policy := range []webgamev2.DriftPolicy{webgamev2.DriftPolicyReportOnly, webgamev2.DriftPolicyCorrect} {
+				webgame := newWebGame("webgame-drift-" + strings.ToLower(string(policy)))
+				webgame.Spec.DriftPolicy = policy
+				createWebGame(webgame)
+				editImage(webgame.GetName())
+
+				if policy == webgamev2.DriftPolicyCorrect {
+					Eventually(func() string { return image(webgame.GetName()) }, timeout, interval).Should(Equal("webgamedevelop/2048:latest"))
+					continue
+				}
+				Eventually(func() []webgamev2.DriftedField {
+					if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+						return nil
+					}
+					return webgame.Status.Drift
+				}, timeout, interval).Should(ContainElement(And(
+					HaveField("Kind", "Deployment"),
+					HaveField("Manager", "kubectl-edit"),
+				)))
+				Expect(meta.IsStatusConditionTrue(webgame.Status.Conditions, webgamev2.ConditionDrifted)).Should(BeTrue())
+				Consistently(func() string { return image(webgame.GetName()) }, time.Second, interval).Should(Equal("webgamedevelop/2048:edited"))
+			}
+		})
+
+		It("replace the deployment when the game type changes", func() {
+			webgame := newWebGame("webgame-replace")
+			createWebGame(webgame)
+
+			var deployment appsv1.Deployment
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return err
+				}
+				webgame.Spec.GameType = "tetris"
+				return k8sClient.Update(ctx, webgame)
+			}, timeout, interval).Should(Succeed())
+
+			// no garbage collector runs, the deployment keeps the orphan finalizer
+			Eventually(func() *metav1.Time {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &deployment); err != nil {
+					return nil
+				}
+				return deployment.GetDeletionTimestamp()
+			}, timeout, interval).ShouldNot(BeNil())
+			Expect(deployment.GetFinalizers()).Should(ContainElement(metav1.FinalizerOrphanDependents))
+
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), webgame); err != nil {
+					return ""
+				}
+				condition := meta.FindStatusCondition(webgame.Status.Conditions, webgamev2.ConditionDeploymentReady)
+				if condition == nil {
+					return ""
+				}
+				return condition.Reason
+			}, timeout, interval).Should(Equal(ReasonReplacing))
+			Expect(webgame.Status.ReplacedSelector).Should(HaveKeyWithValue("gameType", "2048"))
+
+			// the service selects the pods of the replaced deployment until the new one is available
+			var service corev1.Service
+			Expect(k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(webgame), &service)).Should(Succeed())
+			Expect(service.Spec.Selector).Should(HaveKeyWithValue("gameType", "2048"))
+		})
 	})
 })
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+
+// reconcileDisruptionBudget creates or updates the disruption budget of the game pods, and reports the
+// disruptions it allows. A game with a single replica has no budget, it could never be drained.
+func (r *WebGameReconciler) reconcileDisruptionBudget(ctx context.Context, webgame *webgamev2.WebGame, deployment *appsv1.Deployment, selector map[string]string, status *webgamev2.WebGameStatus) error {
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas <= 1 {
+		status.DisruptionsAllowed = nil
+		return r.deleteChild(ctx, webgame, &policyv1.PodDisruptionBudget{})
+	}
+
+	var pdb policyv1.PodDisruptionBudget
+	pdb.SetNamespace(webgame.GetNamespace())
+	pdb.SetName(webgame.GetName())
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &pdb, func() error {
+		pdb.SetLabels(labels.Merge(pdb.GetLabels(), webgame.GetLabels()))
+		pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+		pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable = nil, nil
+		switch d := webgame.Spec.Disruption; {
+		case d != nil && d.MinAvailable != nil:
+			pdb.Spec.MinAvailable = d.MinAvailable
+		case d != nil && d.MaxUnavailable != nil:
+			pdb.Spec.MaxUnavailable = d.MaxUnavailable
+		default:
+			maxUnavailable := intstr.FromInt(1)
+			pdb.Spec.MaxUnavailable = &maxUnavailable
+		}
+		return controllerutil.SetControllerReference(webgame, &pdb, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("poddisruptionbudget changed", "res", res)
+		r.recordChildChange(webgame, &pdb, res)
+	}
+
+	// the budget status is only meaningful once the disruption controller has observed it
+	status.DisruptionsAllowed = nil
+	if pdb.Status.ObservedGeneration == pdb.GetGeneration() {
+		allowed := pdb.Status.DisruptionsAllowed
+		status.DisruptionsAllowed = &allowed
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// Event reasons of the game lifecycle. Warnings use the reason of the Degraded condition.
+const (
+	EventReasonCreated         = "Created"
+	EventReasonUpdated         = "Updated"
+	EventReasonDeleted         = "Deleted"
+	EventReasonRolloutStarted  = "RolloutStarted"
+	EventReasonRolloutFinished = "RolloutFinished"
+	EventReasonAddressChanged  = "AddressChanged"
+	EventReasonRecovered       = "Recovered"
+)
+
+// kindOf returns the kind of a child, typed objects have no kind set.
+func (r *WebGameReconciler) kindOf(obj client.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return "object"
+	}
+	return gvk.Kind
+}
+
+// recordChildChange records an event when a child of the webgame was created or updated.
+func (r *WebGameReconciler) recordChildChange(webgame *webgamev2.WebGame, child client.Object, res controllerutil.OperationResult) {
+	reason := EventReasonUpdated
+	switch res {
+	case controllerutil.OperationResultNone:
+		return
+	case controllerutil.OperationResultCreated:
+		reason = EventReasonCreated
+	}
+	r.Recorder.Eventf(webgame, corev1.EventTypeNormal, reason, "%s %s %s", r.kindOf(child), child.GetName(), res)
+}
+
+// recordTransitions records the events of the changes from the previous to the current status: rollouts of the
+// deployment, a new game address, drift of the children reported only, and the game becoming degraded or
+// recovering. Events are only recorded on transitions, so a game failing the same way on every reconcile records
+// a single warning; the event recorder aggregates the repeated transitions of a flapping game.
+func (r *WebGameReconciler) recordTransitions(webgame *webgamev2.WebGame, previous, current *webgamev2.WebGameStatus) {
+	rollingOut := func(c *metav1.Condition) bool { return c != nil && c.Reason == ReasonRollingOut }
+	wasDeployment := meta.FindStatusCondition(previous.Conditions, webgamev2.ConditionDeploymentReady)
+	deployment := meta.FindStatusCondition(current.Conditions, webgamev2.ConditionDeploymentReady)
+	switch {
+	case rollingOut(deployment) && !rollingOut(wasDeployment):
+		r.Recorder.Event(webgame, corev1.EventTypeNormal, EventReasonRolloutStarted, deployment.Message)
+	case rollingOut(wasDeployment) && deployment != nil && deployment.Status == metav1.ConditionTrue:
+		r.Recorder.Event(webgame, corev1.EventTypeNormal, EventReasonRolloutFinished, deployment.Message)
+	}
+
+	if current.GameAddress != "" && current.GameAddress != previous.GameAddress {
+		r.Recorder.Eventf(webgame, corev1.EventTypeNormal, EventReasonAddressChanged, "game address is %s", current.GameAddress)
+	}
+
+	if len(current.Drift) != 0 && !equality.Semantic.DeepEqual(current.Drift, previous.Drift) {
+		r.Recorder.Event(webgame, corev1.EventTypeWarning, EventReasonDriftDetected, driftMessage(current.Drift))
+	}
+
+	wasDegraded := meta.FindStatusCondition(previous.Conditions, webgamev2.ConditionDegraded)
+	degraded := meta.FindStatusCondition(current.Conditions, webgamev2.ConditionDegraded)
+	isDegraded := func(c *metav1.Condition) bool { return c != nil && c.Status == metav1.ConditionTrue }
+	switch {
+	case isDegraded(degraded) && (!isDegraded(wasDegraded) || wasDegraded.Reason != degraded.Reason):
+		r.Recorder.Event(webgame, corev1.EventTypeWarning, degraded.Reason, degraded.Message)
+	case isDegraded(wasDegraded) && !isDegraded(degraded):
+		r.Recorder.Event(webgame, corev1.EventTypeNormal, EventReasonRecovered, "game is healthy")
+	}
+}
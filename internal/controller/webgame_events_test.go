@@ -0,0 +1,71 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+var _ = Describe("Test lifecycle events", func() {
+	var (
+		recorder *record.FakeRecorder
+		r        *WebGameReconciler
+		webgame  *webgamev2.WebGame
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		r = &WebGameReconciler{Recorder: recorder}
+		webgame = &webgamev2.WebGame{}
+	})
+
+	statusWith := func(conditionType, reason string, conditionStatus metav1.ConditionStatus) *webgamev2.WebGameStatus {
+		status := &webgamev2.WebGameStatus{}
+		setCondition(status, 1, conditionType, conditionStatus, reason, "message")
+		return status
+	}
+
+	It("record the start and the end of a rollout", func() {
+		available := statusWith(webgamev2.ConditionDeploymentReady, ReasonAvailable, metav1.ConditionTrue)
+		rollingOut := statusWith(webgamev2.ConditionDeploymentReady, ReasonRollingOut, metav1.ConditionFalse)
+
+		r.recordTransitions(webgame, available, rollingOut)
+		Expect(recorder.Events).Should(Receive(HavePrefix("Normal RolloutStarted")))
+		r.recordTransitions(webgame, rollingOut, rollingOut)
+		Expect(recorder.Events).ShouldNot(Receive())
+		r.recordTransitions(webgame, rollingOut, available)
+		Expect(recorder.Events).Should(Receive(HavePrefix("Normal RolloutFinished")))
+	})
+
+	It("record a warning once per degraded reason", func() {
+		healthy := statusWith(webgamev2.ConditionDegraded, ReasonAsExpected, metav1.ConditionFalse)
+		degraded := statusWith(webgamev2.ConditionDegraded, ReasonReconcileError, metav1.ConditionTrue)
+
+		r.recordTransitions(webgame, healthy, degraded)
+		Expect(recorder.Events).Should(Receive(HavePrefix("Warning ReconcileError")))
+		r.recordTransitions(webgame, degraded, degraded)
+		Expect(recorder.Events).ShouldNot(Receive())
+		r.recordTransitions(webgame, degraded, statusWith(webgamev2.ConditionDegraded, ReasonImageNotAllowed, metav1.ConditionTrue))
+		Expect(recorder.Events).Should(Receive(HavePrefix("Warning ImageNotAllowed")))
+		r.recordTransitions(webgame, degraded, healthy)
+		Expect(recorder.Events).Should(Receive(HavePrefix("Normal Recovered")))
+	})
+
+	It("record a new game address", func() {
+		r.recordTransitions(webgame, &webgamev2.WebGameStatus{}, &webgamev2.WebGameStatus{GameAddress: "http://localhost/2048/game"})
+		Expect(recorder.Events).Should(Receive(Equal("Normal AddressChanged game address is http://localhost/2048/game")))
+	})
+
+	It("record a warning when new drift is reported", func() {
+		drifted := &webgamev2.WebGameStatus{Drift: []webgamev2.DriftedField{{
+			Kind: "Deployment", Name: "game", Field: ".spec.replicas", Manager: "kubectl-edit",
+		}}}
+		r.recordTransitions(webgame, &webgamev2.WebGameStatus{}, drifted)
+		Expect(recorder.Events).Should(Receive(Equal("Warning DriftDetected Deployment game .spec.replicas changed by kubectl-edit")))
+		r.recordTransitions(webgame, drifted, drifted)
+		Expect(recorder.Events).ShouldNot(Receive())
+	})
+})
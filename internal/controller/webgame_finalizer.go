@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/ingress"
+)
+
+const (
+	webgameFinalizer = "webgame.webgame.tech/finalizer"
+	archiveDataPath  = "/data"
+)
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// finalize runs the deletion policy of a webgame which is being deleted,
+// and removes the finalizer once the webgame can be released.
+func (r *WebGameReconciler) finalize(ctx context.Context, webgame *webgamev2.WebGame) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(webgame, webgameFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// the phase reports the deletion while the policy runs, whichever it is
+	status := webgame.Status.DeepCopy()
+	status.Phase = webgamev2.PhaseTerminating
+	if err := r.syncStatus(ctx, webgame, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// the pool of a static game and the copy of the wildcard certificate are shared, the game leaves them whatever the policy
+	if err := r.leaveStaticPool(ctx, webgame, webgame.Status.Static); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.releaseWildcardCopy(ctx, webgame); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	switch webgame.Spec.DeletionPolicy {
+	case webgamev2.DeletionPolicyRetain:
+		if err := r.orphanChildren(ctx, webgame); err != nil {
+			return ctrl.Result{}, err
+		}
+		logger.Info("child resources retained")
+	case webgamev2.DeletionPolicyArchive:
+		done, err := r.archive(ctx, webgame)
+		if err != nil || !done {
+			return ctrl.Result{}, err
+		}
+		logger.Info("game data archived")
+	}
+
+	controllerutil.RemoveFinalizer(webgame, webgameFinalizer)
+	if err := r.Update(ctx, webgame); err != nil {
+		return ctrl.Result{}, err
+	}
+	logger.Info("webgame released", "policy", webgame.Spec.DeletionPolicy)
+	return ctrl.Result{}, nil
+}
+
+// childKinds are the kinds of the children the reconciler creates for a webgame, under the name of the webgame,
+// of its canary or of its activator, and the extra objects of the ingress dialects.
+var childKinds = []schema.GroupVersionKind{
+	appsv1.SchemeGroupVersion.WithKind("Deployment"),
+	corev1.SchemeGroupVersion.WithKind("Service"),
+	networkingv1.SchemeGroupVersion.WithKind("Ingress"),
+	autoscalingv2.SchemeGroupVersion.WithKind("HorizontalPodAutoscaler"),
+	policyv1.SchemeGroupVersion.WithKind("PodDisruptionBudget"),
+	httpRouteGVK,
+	ingress.MiddlewareGVK,
+}
+
+// orphanChildren removes the webgame owner reference from every child of the webgame,
+// so the garbage collector keeps them after the webgame is gone.
+func (r *WebGameReconciler) orphanChildren(ctx context.Context, webgame *webgamev2.WebGame) error {
+	for _, gvk := range childKinds {
+		children := &unstructured.UnstructuredList{}
+		children.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.List(ctx, children, client.InNamespace(webgame.GetNamespace())); err != nil {
+			// a kind the cluster does not serve has no children
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return err
+		}
+
+		for i := range children.Items {
+			child := &children.Items[i]
+			if !metav1.IsControlledBy(child, webgame) {
+				continue
+			}
+
+			var owners []metav1.OwnerReference
+			for _, owner := range child.GetOwnerReferences() {
+				if owner.UID != webgame.GetUID() {
+					owners = append(owners, owner)
+				}
+			}
+			child.SetOwnerReferences(owners)
+			if err := r.Update(ctx, child); err != nil {
+				return err
+			}
+			log.FromContext(ctx).Info("child retained", "kind", gvk.Kind, "name", child.GetName())
+		}
+	}
+	return nil
+}
+
+// archive runs the archive job and reports its progress in the webgame status,
+// it returns true once the job has succeeded.
+func (r *WebGameReconciler) archive(ctx context.Context, webgame *webgamev2.WebGame) (bool, error) {
+	if webgame.Spec.Archive == nil {
+		return false, fmt.Errorf("deletion policy is %s but spec.archive is not set", webgamev2.DeletionPolicyArchive)
+	}
+
+	var job = batchv1.Job{}
+	job.SetNamespace(webgame.GetNamespace())
+	job.SetName(webgame.GetName() + "-archive")
+	err := r.Get(ctx, client.ObjectKeyFromObject(&job), &job)
+	if errors.IsNotFound(err) {
+		buildArchiveJob(webgame, &job)
+		if err := ctrl.SetControllerReference(webgame, &job, r.Scheme); err != nil {
+			return false, err
+		}
+		err = r.Create(ctx, &job)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	status := webgame.Status.DeepCopy()
+	status.Phase = webgamev2.PhaseTerminating
+	status.Archive = &webgamev2.ArchiveStatus{
+		Phase:          webgamev2.ArchivePending,
+		JobName:        job.GetName(),
+		StartTime:      job.Status.StartTime,
+		CompletionTime: job.Status.CompletionTime,
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.Archive.Phase = webgamev2.ArchiveSucceeded
+		case batchv1.JobFailed:
+			status.Archive.Phase = webgamev2.ArchiveFailed
+			status.Archive.Message = fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+		}
+	}
+	if status.Archive.Phase == webgamev2.ArchivePending && job.Status.Active > 0 {
+		status.Archive.Phase = webgamev2.ArchiveRunning
+		status.Archive.Message = fmt.Sprintf("%d active, %d failed pods", job.Status.Active, job.Status.Failed)
+	}
+
+	if err := r.syncStatus(ctx, webgame, status); err != nil {
+		return false, err
+	}
+	return status.Archive.Phase == webgamev2.ArchiveSucceeded, nil
+}
+
+// buildArchiveJob fills the archive job spec from spec.archive. The job runs the image of the game with its pull
+// secrets by default, taken from the effective spec as they may come from the GameTemplate of the game.
+func buildArchiveJob(webgame *webgamev2.WebGame, job *batchv1.Job) {
+	archive := webgame.Spec.Archive
+	gameContainer := webgame.Spec.Container
+	if webgame.Status.EffectiveSpec != nil {
+		gameContainer = webgame.Status.EffectiveSpec.Container
+	}
+	image := archive.Image
+	if image == "" {
+		image = gameContainer.Image
+	}
+
+	container := corev1.Container{
+		Name:            "archive",
+		Image:           image,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Command:         archive.Command,
+		Args:            archive.Args,
+		Env: append([]corev1.EnvVar{
+			{Name: "WEBGAME_NAME", Value: webgame.GetName()},
+			{Name: "WEBGAME_NAMESPACE", Value: webgame.GetNamespace()},
+			{Name: "WEBGAME_GAME_TYPE", Value: webgame.Spec.GameType},
+			{Name: "WEBGAME_ARCHIVE_DESTINATION", Value: archive.Destination},
+		}, archive.Env...),
+	}
+
+	podSpec := corev1.PodSpec{
+		RestartPolicy:    corev1.RestartPolicyNever,
+		ImagePullSecrets: gameContainer.ImagePullSecrets,
+	}
+	if archive.ClaimName != "" {
+		container.VolumeMounts = []corev1.VolumeMount{{Name: "data", MountPath: archiveDataPath}}
+		podSpec.Volumes = []corev1.Volume{{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: archive.ClaimName},
+			},
+		}}
+	}
+	podSpec.Containers = []corev1.Container{container}
+
+	job.SetLabels(webgame.GetLabels())
+	job.Spec.BackoffLimit = archive.BackoffLimit
+	job.Spec.Template.SetLabels(map[string]string{"instance": webgame.GetName(), "job": "archive"})
+	job.Spec.Template.Spec = podSpec
+}
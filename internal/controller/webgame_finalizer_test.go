@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+var _ = Describe("Test finalizer", func() {
+	DescribeTable("report the Terminating phase whatever the deletion policy",
+		func(policy webgamev2.DeletionPolicy) {
+			webgame := &webgamev2.WebGame{}
+			webgame.SetNamespace("webgames")
+			webgame.SetName("webgame-terminating")
+			webgame.SetFinalizers([]string{webgameFinalizer})
+			webgame.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+			webgame.Spec.DeletionPolicy = policy
+			webgame.Status.Phase = webgamev2.PhaseReady
+
+			// the webgame is gone once released, the phase is read from the status patches
+			var phases []webgamev2.WebGamePhase
+			r := &WebGameReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(webgame).WithStatusSubresource(webgame).
+					WithInterceptorFuncs(interceptor.Funcs{
+						SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+							phases = append(phases, obj.(*webgamev2.WebGame).Status.Phase)
+							return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+						},
+					}).Build(),
+				Scheme: scheme.Scheme,
+			}
+
+			Expect(r.finalize(ctx, webgame)).Should(Equal(ctrl.Result{}))
+			Expect(phases).Should(Equal([]webgamev2.WebGamePhase{webgamev2.PhaseTerminating}))
+			Expect(apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(webgame), webgame))).Should(BeTrue())
+		},
+		Entry("delete policy", webgamev2.DeletionPolicyDelete),
+		Entry("retain policy", webgamev2.DeletionPolicyRetain),
+	)
+})
+
+var _ = Describe("Test retain policy", func() {
+	It("retain every child of a game routed through a gateway", func() {
+		webgame := &webgamev2.WebGame{}
+		webgame.SetNamespace("webgames")
+		webgame.SetName("webgame-retain-gateway")
+		webgame.SetUID("webgame-retain-gateway-uid")
+		webgame.Spec.Routing.Backend = webgamev2.RoutingBackendGateway
+		controller := true
+		owner := metav1.OwnerReference{
+			APIVersion: webgamev2.GroupVersion.String(),
+			Kind:       "WebGame",
+			Name:       webgame.GetName(),
+			UID:        webgame.GetUID(),
+			Controller: &controller,
+		}
+
+		deployment := &appsv1.Deployment{}
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		activator := &corev1.Service{}
+		route := newHTTPRoute()
+		children := []client.Object{deployment, hpa, activator, route}
+		for _, child := range children {
+			child.SetNamespace(webgame.GetNamespace())
+			child.SetName(webgame.GetName())
+			child.SetOwnerReferences([]metav1.OwnerReference{owner})
+		}
+		activator.SetName(activatorName(webgame))
+
+		// the Gateway API is not in the scheme, the cluster serves it
+		mapper := meta.NewDefaultRESTMapper(nil)
+		for gvk := range scheme.Scheme.AllKnownTypes() {
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+		}
+		mapper.Add(httpRouteGVK, meta.RESTScopeNamespace)
+		r := &WebGameReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRESTMapper(mapper).WithObjects(children...).Build(),
+			Scheme: scheme.Scheme,
+		}
+
+		Expect(r.orphanChildren(ctx, webgame)).Should(Succeed())
+		for _, child := range children {
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(child), child)).Should(Succeed())
+			Expect(child.GetOwnerReferences()).Should(BeEmpty(), "%T %s", child, child.GetName())
+		}
+	})
+})
+
+var _ = Describe("Test archive policy", func() {
+	It("archive a game with the image and pull secrets of its template", func() {
+		webgame := &webgamev2.WebGame{}
+		webgame.SetNamespace("webgames")
+		webgame.SetName("webgame-archive-template")
+		webgame.Spec.GameType = "tetris"
+		webgame.Spec.DeletionPolicy = webgamev2.DeletionPolicyArchive
+		webgame.Spec.Archive = &webgamev2.ArchiveSpec{Destination: "s3://archives/tetris"}
+		webgame.Status.EffectiveSpec = webgame.Spec.DeepCopy()
+		webgame.Status.EffectiveSpec.Container = webgamev2.ContainerSpec{
+			Image:            "webgamedevelop/tetris:latest",
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "template-registry"}},
+		}
+		r := &WebGameReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(webgame).WithStatusSubresource(webgame).Build(),
+			Scheme: scheme.Scheme,
+		}
+
+		done, err := r.archive(ctx, webgame)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(done).Should(BeFalse())
+
+		var job batchv1.Job
+		Expect(r.Get(ctx, client.ObjectKey{Namespace: webgame.GetNamespace(), Name: webgame.GetName() + "-archive"}, &job)).Should(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Image).Should(Equal("webgamedevelop/tetris:latest"))
+		Expect(job.Spec.Template.Spec.ImagePullSecrets).Should(Equal([]corev1.LocalObjectReference{{Name: "template-registry"}}))
+	})
+})
@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// httpRouteGVK is the Gateway API HTTPRoute, handled as unstructured so the controller
+// does not depend on the Gateway API module and starts on clusters without its CRDs.
+var httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// gatewayGVK is the Gateway API Gateway, read for the protocol of the listeners of the game routes.
+var gatewayGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"}
+
+// Condition reasons of the HTTPRouteReady condition.
+const (
+	ReasonRouteAccepted    = "Accepted"
+	ReasonRoutePending     = "RoutePending"
+	ReasonRouteNotAccepted = "RouteNotAccepted"
+	ReasonRefsNotResolved  = "RefsNotResolved"
+)
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes/status,verbs=get
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get
+
+func newHTTPRoute() *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	return route
+}
+
+// routingBackend returns the routing backend of the webgame, or the cluster-wide default.
+func (r *WebGameReconciler) routingBackend(webgame *webgamev2.WebGame) webgamev2.RoutingBackend {
+	if webgame.Spec.Routing.Backend != "" {
+		return webgame.Spec.Routing.Backend
+	}
+	return webgamev2.RoutingBackend(r.Config.Routing.Backend)
+}
+
+// parentGateway returns the gateway the HTTPRoute of the webgame attaches to.
+func (r *WebGameReconciler) parentGateway(webgame *webgamev2.WebGame) (map[string]interface{}, error) {
+	var namespace, name, sectionName string
+	switch {
+	case webgame.Spec.Routing.Gateway != nil:
+		gateway := webgame.Spec.Routing.Gateway
+		namespace, name, sectionName = gateway.Namespace, gateway.Name, gateway.SectionName
+	case r.Config.Routing.Gateway != nil:
+		gateway := r.Config.Routing.Gateway
+		namespace, name, sectionName = gateway.Namespace, gateway.Name, gateway.SectionName
+	default:
+		return nil, fmt.Errorf("routing backend %s requires a gateway in the webgame or the controller configuration", webgamev2.RoutingBackendGateway)
+	}
+
+	parent := map[string]interface{}{
+		"group": httpRouteGVK.Group,
+		"kind":  "Gateway",
+		"name":  name,
+	}
+	if namespace != "" {
+		parent["namespace"] = namespace
+	}
+	if sectionName != "" {
+		parent["sectionName"] = sectionName
+	}
+	return parent, nil
+}
+
+// reconcileHTTPRoute creates or updates the HTTPRoute routing the game route to the game service,
+// with a URLRewrite filter stripping the path prefix, and sets the HTTPRouteReady condition.
+func (r *WebGameReconciler) reconcileHTTPRoute(ctx context.Context, webgame *webgamev2.WebGame, service *corev1.Service, gameRoute gameRoute, status *webgamev2.WebGameStatus) (controllerutil.OperationResult, error) {
+	parent, err := r.parentGateway(webgame)
+	if err != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionHTTPRouteReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return controllerutil.OperationResultNone, err
+	}
+
+	route := newHTTPRoute()
+	route.SetNamespace(webgame.GetNamespace())
+	route.SetName(webgame.GetName())
+	mutate := func() error {
+		route.SetLabels(labels.Merge(route.GetLabels(), webgame.GetLabels()))
+		rule := map[string]interface{}{
+			"matches": []interface{}{map[string]interface{}{
+				"path": map[string]interface{}{"type": "PathPrefix", "value": "/"},
+			}},
+			"backendRefs": httpRouteBackendRefs(webgame, service, status),
+		}
+		if gameRoute.Prefix != "" {
+			rule["matches"] = []interface{}{map[string]interface{}{
+				"path": map[string]interface{}{"type": "PathPrefix", "value": gameRoute.Prefix},
+			}}
+		}
+		if gameRoute.Prefix != "" && !gameRoute.KeepPrefix {
+			rule["filters"] = []interface{}{map[string]interface{}{
+				"type": "URLRewrite",
+				"urlRewrite": map[string]interface{}{
+					"path": map[string]interface{}{"type": "ReplacePrefixMatch", "replacePrefixMatch": "/"},
+				},
+			}}
+		}
+		spec := map[string]interface{}{
+			"parentRefs": []interface{}{parent},
+			"rules":      []interface{}{rule},
+		}
+		if gameRoute.Host != "" || webgame.Spec.Routing.TLS != nil {
+			spec["hostnames"] = []interface{}{gameRoute.addressHost(webgame)}
+		}
+		if err := unstructured.SetNestedField(route.Object, spec, "spec"); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(webgame, route, r.Scheme)
+	}
+
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, route, mutate)
+	if err != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionHTTPRouteReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return res, err
+	}
+
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("httproute changed", "res", res)
+		r.recordChildChange(webgame, route, res)
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionHTTPRouteReady, metav1.ConditionFalse, ReasonRoutePending, fmt.Sprintf("httproute %s", res))
+		return res, nil
+	}
+
+	conditionStatus, reason, message := httpRouteCondition(route)
+	setCondition(status, webgame.GetGeneration(), webgamev2.ConditionHTTPRouteReady, conditionStatus, reason, message)
+	return res, nil
+}
+
+// gatewayServesHTTPS returns true when the parent gateway of the webgame terminates TLS for the HTTPRoute:
+// the listener of the section name has the HTTPS protocol, or one of the listeners when the route names none.
+// The HTTPRoute only carries the hostname of spec.routing.tls, the certificate is configured on the listener.
+func (r *WebGameReconciler) gatewayServesHTTPS(ctx context.Context, webgame *webgamev2.WebGame) (bool, error) {
+	parent, err := r.parentGateway(webgame)
+	if err != nil {
+		return false, err
+	}
+	gateway := &unstructured.Unstructured{}
+	gateway.SetGroupVersionKind(gatewayGVK)
+	key := client.ObjectKey{Namespace: webgame.GetNamespace(), Name: parent["name"].(string)}
+	if namespace, ok := parent["namespace"].(string); ok {
+		key.Namespace = namespace
+	}
+	if err := r.Get(ctx, key, gateway); err != nil {
+		// the route condition reports a missing gateway, the game is addressed over plain HTTP until it exists
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	sectionName, _ := parent["sectionName"].(string)
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+	for _, l := range listeners {
+		listener, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(listener, "name")
+		protocol, _, _ := unstructured.NestedString(listener, "protocol")
+		if (sectionName == "" || name == sectionName) && protocol == "HTTPS" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// httpRouteBackendRefs returns the game service as backend of the route, and the canary service
+// with the weight of the rollout while a canary runs.
+func httpRouteBackendRefs(webgame *webgamev2.WebGame, service *corev1.Service, status *webgamev2.WebGameStatus) []interface{} {
+	// defaulted fields are set too, so the route is not updated on every reconcile
+	backendRef := func(name string, port, weight int32) interface{} {
+		return map[string]interface{}{
+			"group":  "",
+			"kind":   "Service",
+			"name":   name,
+			"port":   int64(port),
+			"weight": int64(weight),
+		}
+	}
+	weight, ok := canaryWeight(status)
+	if !ok {
+		return []interface{}{backendRef(service.GetName(), service.Spec.Ports[0].Port, 1)}
+	}
+	return []interface{}{
+		backendRef(service.GetName(), service.Spec.Ports[0].Port, 100-weight),
+		backendRef(canaryName(webgame), webgame.Spec.Networking.ServerPort, weight),
+	}
+}
+
+// httpRouteCondition derives the HTTPRouteReady condition from the Accepted and ResolvedRefs
+// conditions the gateway controllers report for each parent of the route.
+func httpRouteCondition(route *unstructured.Unstructured) (metav1.ConditionStatus, string, string) {
+	parents, _, _ := unstructured.NestedSlice(route.Object, "status", "parents")
+	if len(parents) == 0 {
+		return metav1.ConditionFalse, ReasonRoutePending, "waiting for the gateway to accept the route"
+	}
+
+	for _, p := range parents {
+		parent, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		items, _, _ := unstructured.NestedSlice(parent, "conditions")
+		var conditions []metav1.Condition
+		for _, item := range items {
+			condition, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			conditionType, _, _ := unstructured.NestedString(condition, "type")
+			conditionStatus, _, _ := unstructured.NestedString(condition, "status")
+			reason, _, _ := unstructured.NestedString(condition, "reason")
+			message, _, _ := unstructured.NestedString(condition, "message")
+			conditions = append(conditions, metav1.Condition{Type: conditionType, Status: metav1.ConditionStatus(conditionStatus), Reason: reason, Message: message})
+		}
+
+		for _, check := range []struct{ conditionType, reason string }{
+			{"Accepted", ReasonRouteNotAccepted},
+			{"ResolvedRefs", ReasonRefsNotResolved},
+		} {
+			condition := meta.FindStatusCondition(conditions, check.conditionType)
+			switch {
+			case condition == nil || condition.Status == metav1.ConditionUnknown:
+				return metav1.ConditionFalse, ReasonRoutePending, fmt.Sprintf("waiting for the gateway to report %s", check.conditionType)
+			case condition.Status == metav1.ConditionFalse:
+				return metav1.ConditionFalse, check.reason, fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+			}
+		}
+	}
+	return metav1.ConditionTrue, ReasonRouteAccepted, "route accepted by the gateway"
+}
+
+// deleteChild deletes the child of the webgame with the type and the name of obj, the name of the webgame when
+// obj has none, when it exists and is owned by the webgame. A child kind the cluster does not serve has nothing to delete.
+func (r *WebGameReconciler) deleteChild(ctx context.Context, webgame *webgamev2.WebGame, obj client.Object) error {
+	key := client.ObjectKeyFromObject(obj)
+	if key.Name == "" {
+		key = client.ObjectKeyFromObject(webgame)
+	}
+	if err := r.Get(ctx, key, obj); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(obj, webgame) {
+		return nil
+	}
+	if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	log.FromContext(ctx).Info("unused child deleted", "kind", fmt.Sprintf("%T", obj), "name", key.Name)
+	r.Recorder.Eventf(webgame, corev1.EventTypeNormal, EventReasonDeleted, "%s %s deleted", r.kindOf(obj), key.Name)
+	return nil
+}
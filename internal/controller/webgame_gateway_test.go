@@ -0,0 +1,94 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/config"
+)
+
+var _ = Describe("Test HTTPRoute status", func() {
+	routeWithConditions := func(conditions ...interface{}) *unstructured.Unstructured {
+		route := newHTTPRoute()
+		Expect(unstructured.SetNestedSlice(route.Object, []interface{}{
+			map[string]interface{}{"conditions": conditions},
+		}, "status", "parents")).Should(Succeed())
+		return route
+	}
+
+	It("wait for the gateway before it reports the route", func() {
+		conditionStatus, reason, _ := httpRouteCondition(newHTTPRoute())
+		Expect(conditionStatus).Should(Equal(metav1.ConditionFalse))
+		Expect(reason).Should(Equal(ReasonRoutePending))
+	})
+
+	It("report a route rejected by the gateway", func() {
+		route := routeWithConditions(
+			map[string]interface{}{"type": "Accepted", "status": "False", "reason": "NotAllowedByListeners", "message": "no listener allows the route"},
+			map[string]interface{}{"type": "ResolvedRefs", "status": "True", "reason": "ResolvedRefs"},
+		)
+		conditionStatus, reason, message := httpRouteCondition(route)
+		Expect(conditionStatus).Should(Equal(metav1.ConditionFalse))
+		Expect(reason).Should(Equal(ReasonRouteNotAccepted))
+		Expect(message).Should(ContainSubstring("NotAllowedByListeners"))
+	})
+
+	It("report a route with unresolved backends", func() {
+		route := routeWithConditions(
+			map[string]interface{}{"type": "Accepted", "status": "True", "reason": "Accepted"},
+			map[string]interface{}{"type": "ResolvedRefs", "status": "False", "reason": "BackendNotFound"},
+		)
+		_, reason, _ := httpRouteCondition(route)
+		Expect(reason).Should(Equal(ReasonRefsNotResolved))
+	})
+
+	It("report an accepted route as ready", func() {
+		route := routeWithConditions(
+			map[string]interface{}{"type": "Accepted", "status": "True", "reason": "Accepted"},
+			map[string]interface{}{"type": "ResolvedRefs", "status": "True", "reason": "ResolvedRefs"},
+		)
+		conditionStatus, reason, _ := httpRouteCondition(route)
+		Expect(conditionStatus).Should(Equal(metav1.ConditionTrue))
+		Expect(reason).Should(Equal(ReasonRouteAccepted))
+	})
+})
+
+var _ = Describe("Test gateway address", func() {
+	newGateway := func(listeners ...interface{}) *unstructured.Unstructured {
+		gateway := &unstructured.Unstructured{}
+		gateway.SetGroupVersionKind(gatewayGVK)
+		gateway.SetNamespace("gateways")
+		gateway.SetName("games")
+		Expect(unstructured.SetNestedSlice(gateway.Object, listeners, "spec", "listeners")).Should(Succeed())
+		return gateway
+	}
+	http := map[string]interface{}{"name": "http", "protocol": "HTTP", "port": int64(80)}
+	https := map[string]interface{}{"name": "https", "protocol": "HTTPS", "port": int64(443)}
+
+	DescribeTable("report https only when the listener of the route terminates TLS",
+		func(sectionName string, gateway *unstructured.Unstructured, expected bool) {
+			builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			if gateway != nil {
+				builder = builder.WithObjects(gateway)
+			}
+			r := &WebGameReconciler{Client: builder.Build(), Scheme: scheme.Scheme, Config: config.Default()}
+			webgame := &webgamev2.WebGame{}
+			webgame.SetNamespace("default")
+			webgame.SetName("webgame-gateway")
+			webgame.Spec.Routing.Backend = webgamev2.RoutingBackendGateway
+			webgame.Spec.Routing.Gateway = &webgamev2.GatewayReference{Namespace: "gateways", Name: "games", SectionName: sectionName}
+
+			Expect(r.gatewayServesHTTPS(ctx, webgame)).Should(Equal(expected))
+		},
+		Entry("HTTPS listener", "https", newGateway(http, https), true),
+		Entry("HTTP listener", "http", newGateway(http, https), false),
+		Entry("any HTTPS listener", "", newGateway(http, https), true),
+		Entry("HTTP listeners only", "", newGateway(http), false),
+		Entry("missing gateway", "", nil, false),
+	)
+})
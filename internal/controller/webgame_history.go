@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// Reasons of the rollback events.
+const (
+	EventReasonRolledBack               = "RolledBack"
+	EventReasonAutoRolledBack           = "AutoRolledBack"
+	EventReasonRollbackRevisionNotFound = "RollbackRevisionNotFound"
+)
+
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// revisionContainer returns the game container spec running image.
+func revisionContainer(webgame *webgamev2.WebGame, image string) *webgamev2.ContainerSpec {
+	container := webgame.Spec.Container.DeepCopy()
+	container.Image = image
+	return container
+}
+
+// specHash returns the hash of the game container spec running image, with the server port it listens on.
+func specHash(webgame *webgamev2.WebGame, image string) string {
+	data, _ := json.Marshal(struct {
+		Container  *webgamev2.ContainerSpec `json:"container"`
+		ServerPort int32                    `json:"serverPort"`
+	}{revisionContainer(webgame, image), webgame.Spec.Networking.ServerPort})
+
+	hasher := fnv.New32a()
+	hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// recordRevision adds the revision running image to the history when its spec changed. A spec applied before
+// moves to a new revision like with Deployments, and the oldest revisions are dropped past the history limit.
+func recordRevision(webgame *webgamev2.WebGame, image string, status *webgamev2.WebGameStatus) {
+	hash := specHash(webgame, image)
+	history := status.History
+	if n := len(history); n != 0 && history[n-1].SpecHash == hash {
+		return
+	}
+
+	var next int64 = 1
+	if n := len(history); n != 0 {
+		next = history[n-1].Revision + 1
+	}
+	kept := make([]webgamev2.Revision, 0, len(history)+1)
+	for _, revision := range history {
+		if revision.SpecHash != hash {
+			kept = append(kept, revision)
+		}
+	}
+	kept = append(kept, webgamev2.Revision{
+		Revision:   next,
+		Image:      image,
+		SpecHash:   hash,
+		Container:  revisionContainer(webgame, image),
+		ServerPort: webgame.Spec.Networking.ServerPort,
+		Time:       metav1.Now(),
+		Outcome:    webgamev2.RevisionProgressing,
+	})
+
+	limit := webgamev2.DefaultRevisionHistoryLimit
+	if l := webgame.Spec.Rollout.RevisionHistoryLimit; l != nil {
+		limit = int(*l)
+	}
+	if len(kept) > limit {
+		kept = kept[len(kept)-limit:]
+	}
+	status.History = kept
+}
+
+// revisionOutcome sets the outcome of the newest revision from the DeploymentReady and ContainersHealthy
+// conditions. It returns true when the revision just failed.
+func revisionOutcome(status *webgamev2.WebGameStatus) bool {
+	n := len(status.History)
+	if n == 0 || status.History[n-1].Outcome != webgamev2.RevisionProgressing {
+		return false
+	}
+	revision := &status.History[n-1]
+
+	deployment := meta.FindStatusCondition(status.Conditions, webgamev2.ConditionDeploymentReady)
+	containers := meta.FindStatusCondition(status.Conditions, webgamev2.ConditionContainersHealthy)
+	switch {
+	case deployment != nil && deployment.Reason == ReasonProgressDeadlineExceeded:
+		revision.Outcome, revision.Message = webgamev2.RevisionFailed, deployment.Message
+	case containers != nil && containers.Reason == ReasonCrashLooping:
+		revision.Outcome, revision.Message = webgamev2.RevisionFailed, containers.Message
+	case deployment != nil && deployment.Status == metav1.ConditionTrue:
+		revision.Outcome, revision.Message = webgamev2.RevisionSucceeded, ""
+		return false
+	default:
+		return false
+	}
+	return true
+}
+
+// lastSucceededRevision returns the newest successful revision before the newest one.
+func lastSucceededRevision(history []webgamev2.Revision) *webgamev2.Revision {
+	for i := len(history) - 2; i >= 0; i-- {
+		if history[i].Outcome == webgamev2.RevisionSucceeded {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+// restoreRevision sets the container and the server port of revision on spec, the spec hash of the
+// revision is then the one of the spec.
+func restoreRevision(spec *webgamev2.WebGameSpec, revision *webgamev2.Revision) {
+	// revisions recorded without their spec only restore their image
+	if revision.Container == nil {
+		spec.Container.Image = revision.Image
+		return
+	}
+	spec.Container = *revision.Container.DeepCopy()
+	spec.Networking.ServerPort = revision.ServerPort
+}
+
+// rollbackTo restores the revision in spec.rollbackTo and clears the field. It returns true when
+// the webgame was updated, the update triggers the next reconcile.
+func (r *WebGameReconciler) rollbackTo(ctx context.Context, webgame *webgamev2.WebGame) (bool, error) {
+	if webgame.Spec.RollbackTo == nil {
+		return false, nil
+	}
+	number := *webgame.Spec.RollbackTo
+	webgame.Spec.RollbackTo = nil
+
+	var revision *webgamev2.Revision
+	for i := range webgame.Status.History {
+		if webgame.Status.History[i].Revision == number {
+			revision = &webgame.Status.History[i]
+		}
+	}
+	if revision == nil {
+		r.Recorder.Eventf(webgame, corev1.EventTypeWarning, EventReasonRollbackRevisionNotFound, "revision %d is not in the history", number)
+	} else {
+		restoreRevision(&webgame.Spec, revision)
+		r.Recorder.Eventf(webgame, corev1.EventTypeNormal, EventReasonRolledBack, "rolled back to revision %d, image %s", number, revision.Image)
+		log.FromContext(ctx).Info("rolled back", "revision", number, "image", revision.Image)
+	}
+	return true, r.Update(ctx, webgame)
+}
+
+// autoRollback restores the last successful revision when the newest revision failed
+// and automatic rollback is enabled. It returns true when the webgame was updated.
+func (r *WebGameReconciler) autoRollback(ctx context.Context, webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) (bool, error) {
+	if !revisionOutcome(status) || !webgame.Spec.Rollout.AutoRollback {
+		return false, nil
+	}
+	failed := status.History[len(status.History)-1]
+	revision := lastSucceededRevision(status.History)
+	if revision == nil || revision.SpecHash == specHash(webgame, webgame.Spec.Container.Image) {
+		return false, nil
+	}
+
+	// webgame holds the effective spec, the revision is restored on the stored spec
+	var latest webgamev2.WebGame
+	if err := r.Get(ctx, client.ObjectKeyFromObject(webgame), &latest); err != nil {
+		return false, err
+	}
+	restoreRevision(&latest.Spec, revision)
+	if err := r.Update(ctx, &latest); err != nil {
+		return false, err
+	}
+	r.Recorder.Eventf(webgame, corev1.EventTypeWarning, EventReasonAutoRolledBack, "revision %d failed: %s, rolled back to revision %d, image %s",
+		failed.Revision, failed.Message, revision.Revision, revision.Image)
+	log.FromContext(ctx).Info("automatically rolled back", "failed", failed.Revision, "revision", revision.Revision, "image", revision.Image)
+	return true, nil
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+const (
+	// lastRequestAnnotation is when the activator last received a request for the game, in RFC 3339.
+	lastRequestAnnotation = "webgame.webgame.tech/last-request"
+	// wakeAnnotation is when the activator received a request for the game while it was idle, in RFC 3339.
+	wakeAnnotation = "webgame.webgame.tech/wake"
+)
+
+// Event reasons of the idle policy.
+const (
+	EventReasonIdled = "Idled"
+	EventReasonWoken = "Woken"
+)
+
+// activatorName returns the name of the service routing the requests of an idle game to the activator.
+func activatorName(webgame *webgamev2.WebGame) string {
+	return webgame.GetName() + "-activator"
+}
+
+// isIdle returns true while the game is scaled to zero by the idle policy.
+func isIdle(status *webgamev2.WebGameStatus) bool {
+	return status.Idle != nil && status.Idle.Phase == webgamev2.IdlePhaseIdle
+}
+
+// annotationTime returns the time stored in the annotation key of the webgame.
+func annotationTime(webgame *webgamev2.WebGame, key string) (time.Time, bool) {
+	value, ok := webgame.GetAnnotations()[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, err == nil
+}
+
+// reconcileIdle updates the idle status of the webgame from the annotations set by the activator, and returns
+// when the game goes idle unless a request comes in. The game deployment is scaled to zero while the game is idle.
+func (r *WebGameReconciler) reconcileIdle(webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) time.Duration {
+	if webgame.Spec.Idle == nil {
+		status.Idle = nil
+		return 0
+	}
+
+	now := time.Now()
+	idle := status.Idle
+	if idle == nil {
+		idle = &webgamev2.IdleStatus{Phase: webgamev2.IdlePhaseAwake, LastTransitionTime: metav1.NewTime(now)}
+		status.Idle = idle
+	}
+	if lastRequest, ok := annotationTime(webgame, lastRequestAnnotation); ok && (idle.LastRequestTime == nil || lastRequest.After(idle.LastRequestTime.Time)) {
+		idle.LastRequestTime = &metav1.Time{Time: lastRequest}
+	}
+
+	if idle.Phase == webgamev2.IdlePhaseIdle {
+		// only a request received after the game went idle wakes it
+		wake, ok := annotationTime(webgame, wakeAnnotation)
+		if !ok || !wake.After(idle.LastTransitionTime.Time) {
+			return 0
+		}
+		idle.Phase = webgamev2.IdlePhaseAwake
+		idle.LastTransitionTime = metav1.NewTime(now)
+		idleTransitions.WithLabelValues(webgame.GetNamespace(), webgame.Spec.GameType, "wake").Inc()
+		r.Recorder.Event(webgame, corev1.EventTypeNormal, EventReasonWoken, "request received, game scaled up")
+	}
+
+	// a woken game gets a full timeout even when its last request is older
+	timeout := webgame.Spec.Idle.Timeout.Duration
+	deadline := idle.LastTransitionTime.Add(timeout)
+	if idle.LastRequestTime != nil && idle.LastRequestTime.Add(timeout).After(deadline) {
+		deadline = idle.LastRequestTime.Add(timeout)
+	}
+	if now.Before(deadline) {
+		return deadline.Sub(now)
+	}
+
+	idle.Phase = webgamev2.IdlePhaseIdle
+	idle.LastTransitionTime = metav1.NewTime(now)
+	idleTransitions.WithLabelValues(webgame.GetNamespace(), webgame.Spec.GameType, "idle").Inc()
+	r.Recorder.Eventf(webgame, corev1.EventTypeNormal, EventReasonIdled, "no request for %s, game scaled to zero", timeout)
+	return 0
+}
+
+// reconcileActivatorService creates or updates the ExternalName service sending the requests of a game with
+// an idle policy to the activator, or deletes it when the game has none. It returns the service routed to.
+// The game is routed to the activator while it runs too, the requests it proxies are the only measure of the
+// activity of the game, so the activator stays on the request path of every game with an idle policy.
+func (r *WebGameReconciler) reconcileActivatorService(ctx context.Context, webgame *webgamev2.WebGame, service *corev1.Service) (*corev1.Service, error) {
+	activatorService := &corev1.Service{}
+	activatorService.SetNamespace(webgame.GetNamespace())
+	activatorService.SetName(activatorName(webgame))
+	if webgame.Spec.Idle == nil {
+		return service, r.deleteChild(ctx, webgame, activatorService)
+	}
+
+	activator := r.Config.Idle.Activator
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, activatorService, func() error {
+		activatorService.SetLabels(labels.Merge(activatorService.GetLabels(), webgame.GetLabels()))
+		activatorService.Spec.Type = corev1.ServiceTypeExternalName
+		activatorService.Spec.ExternalName = fmt.Sprintf("%s.%s.svc.%s", activator.Name, activator.Namespace, r.Config.Idle.ClusterDomain)
+		activatorService.Spec.Ports = []corev1.ServicePort{{
+			Name:       "http",
+			Port:       activator.Port,
+			TargetPort: intstr.FromInt(int(activator.Port)),
+			Protocol:   corev1.ProtocolTCP,
+		}}
+		return controllerutil.SetControllerReference(webgame, activatorService, r.Scheme)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("activator service changed", "res", res)
+		r.recordChildChange(webgame, activatorService, res)
+	}
+	return activatorService, nil
+}
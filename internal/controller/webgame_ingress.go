@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	networkingv1ac "k8s.io/client-go/applyconfigurations/networking/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/ingress"
+)
+
+// dialectAnnotation records the dialect an ingress was configured with,
+// so the extra objects of the previous dialect are deleted when it changes.
+const dialectAnnotation = "webgame.webgame.tech/ingress-dialect"
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=traefik.io,resources=middlewares,verbs=get;list;watch;create;update;patch;delete
+
+// ingressDialect returns the dialect of an ingress class: the one set in the controller configuration,
+// else the one of the IngressClass controller, else the default dialect.
+func (r *WebGameReconciler) ingressDialect(ctx context.Context, class string) (ingress.Dialect, error) {
+	if name, ok := r.Config.Ingress.Dialects[class]; ok {
+		return ingress.Lookup(name)
+	}
+
+	var ingressClass networkingv1.IngressClass
+	if err := r.Get(ctx, client.ObjectKey{Name: class}, &ingressClass); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if dialect, ok := ingress.ForController(ingressClass.Spec.Controller); ok {
+		return dialect, nil
+	}
+	return ingress.Lookup(r.Config.Ingress.DefaultDialect)
+}
+
+// reconcileIngress creates or updates the ingress routing the game route to the game service, with the path
+// rewritten by the dialect of the ingress class, and sets the IngressReady condition.
+func (r *WebGameReconciler) reconcileIngress(ctx context.Context, webgame *webgamev2.WebGame, service *corev1.Service, gameRoute gameRoute, tlsSecret string, status *webgamev2.WebGameStatus) (controllerutil.OperationResult, error) {
+	logger := log.FromContext(ctx)
+
+	dialect, err := r.ingressDialect(ctx, webgame.Spec.Networking.IngressClass)
+	if err != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionIngressReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return controllerutil.OperationResultNone, err
+	}
+
+	route := ingress.Route{
+		Namespace:  webgame.GetNamespace(),
+		Name:       webgame.GetName(),
+		Prefix:     gameRoute.Prefix,
+		KeepPrefix: gameRoute.KeepPrefix,
+		TLS:        tlsSecret != "",
+	}
+	if tls := webgame.Spec.Routing.TLS; tls != nil {
+		route.RedirectHTTP = tls.RedirectHTTP == nil || *tls.RedirectHTTP
+		if tls.HSTS != nil {
+			route.HSTS = &ingress.HSTS{MaxAge: tls.HSTS.MaxAge, IncludeSubDomains: tls.HSTS.IncludeSubDomains, Preload: tls.HSTS.Preload}
+		}
+	}
+	config := dialect.Configure(route)
+
+	// the objects the ingress depends on are created first
+	for _, obj := range config.Objects {
+		if err := r.reconcileIngressObject(ctx, webgame, obj); err != nil {
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionIngressReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+			return controllerutil.OperationResultNone, err
+		}
+	}
+
+	annotations := labels.Merge(certManagerAnnotations(webgame.Spec.Routing.TLS), config.Annotations)
+	annotations[dialectAnnotation] = dialect.Name()
+
+	// annotations applied before and left out now, like the ones of another dialect, are removed by the apply
+	var ingressObj networkingv1.Ingress
+	if err := r.Get(ctx, client.ObjectKeyFromObject(webgame), &ingressObj); client.IgnoreNotFound(err) != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionIngressReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return controllerutil.OperationResultNone, err
+	}
+	previousDialect := ingressObj.GetAnnotations()[dialectAnnotation]
+	configuration := networkingv1ac.Ingress(webgame.GetName(), webgame.GetNamespace()).
+		WithLabels(webgame.GetLabels()).
+		WithAnnotations(annotations).
+		WithOwnerReferences(ownerReference(webgame)).
+		WithSpec(ingressSpec(webgame, service.GetName(), service.Spec.Ports[0].Port, gameRoute, config, tlsSecret))
+
+	res, err := r.applyChild(ctx, webgame, &ingressObj, configuration, status)
+	if err != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionIngressReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return res, err
+	}
+
+	// objects no longer referenced by the ingress are deleted last
+	unused := config.Unused
+	if previousDialect != "" && previousDialect != dialect.Name() {
+		if previous, err := ingress.Lookup(previousDialect); err == nil {
+			previousConfig := previous.Configure(route)
+			unused = append(previousConfig.Objects, previousConfig.Unused...)
+		}
+	}
+	for _, obj := range unused {
+		if err := r.deleteChild(ctx, webgame, obj); err != nil {
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionIngressReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+			return res, err
+		}
+	}
+
+	if err := r.reconcileCanaryIngress(ctx, webgame, dialect, gameRoute, config, tlsSecret, status); err != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionIngressReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return res, err
+	}
+
+	if len(config.Unsupported) != 0 {
+		// the game is served, without the features the ingress controller is configured for globally
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionIngressReady, metav1.ConditionTrue, ReasonFeatureUnsupported,
+			fmt.Sprintf("ingress %s with %s dialect, which does not support %s", res, dialect.Name(), strings.Join(config.Unsupported, ", ")))
+	} else {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionIngressReady, metav1.ConditionTrue, ReasonSynced, fmt.Sprintf("ingress %s with %s dialect", res, dialect.Name()))
+	}
+	if res != controllerutil.OperationResultNone {
+		logger.Info("ingress changed", "res", res, "dialect", dialect.Name())
+		r.recordChildChange(webgame, &ingressObj, res)
+	}
+	return res, nil
+}
+
+// ingressSpec returns the apply configuration of the spec of an ingress routing the game route to port of the service.
+func ingressSpec(webgame *webgamev2.WebGame, service string, port int32, gameRoute gameRoute, config ingress.Config, tlsSecret string) *networkingv1ac.IngressSpecApplyConfiguration {
+	path := networkingv1ac.HTTPIngressPath().
+		WithPathType(config.PathType).
+		WithBackend(networkingv1ac.IngressBackend().
+			WithService(networkingv1ac.IngressServiceBackend().
+				WithName(service).
+				WithPort(networkingv1ac.ServiceBackendPort().WithNumber(port))))
+	if config.Path != "" {
+		path.WithPath(config.Path)
+	}
+	rule := networkingv1ac.IngressRule().WithHTTP(networkingv1ac.HTTPIngressRuleValue().WithPaths(path))
+	if gameRoute.Host != "" {
+		rule.WithHost(gameRoute.Host)
+	}
+
+	spec := networkingv1ac.IngressSpec().WithRules(rule)
+	// without the defaulting webhook a game may have no class, the default IngressClass of the cluster serves it
+	if class := webgame.Spec.Networking.IngressClass; class != "" {
+		spec.WithIngressClassName(class)
+	}
+	if tlsSecret != "" {
+		spec.WithTLS(networkingv1ac.IngressTLS().
+			WithHosts(gameRoute.addressHost(webgame)).
+			WithSecretName(tlsSecret))
+	}
+	return spec
+}
+
+// reconcileCanaryIngress creates or updates the ingress sending the weight of the rollout to the canary service,
+// or deletes it when no canary runs. The canary ingress has the same rule as the game ingress.
+func (r *WebGameReconciler) reconcileCanaryIngress(ctx context.Context, webgame *webgamev2.WebGame, dialect ingress.Dialect, gameRoute gameRoute, config ingress.Config, tlsSecret string, status *webgamev2.WebGameStatus) error {
+	canaryIngress := networkingv1.Ingress{}
+	canaryIngress.SetNamespace(webgame.GetNamespace())
+	canaryIngress.SetName(canaryName(webgame))
+
+	weight, ok := canaryWeight(status)
+	if !ok {
+		return r.deleteChild(ctx, webgame, &canaryIngress)
+	}
+	canary, ok := dialect.(ingress.Canary)
+	if !ok {
+		return fmt.Errorf("canary rollouts are not supported by the %s ingress dialect, use the nginx dialect or the Gateway routing backend", dialect.Name())
+	}
+
+	configuration := networkingv1ac.Ingress(canaryName(webgame), webgame.GetNamespace()).
+		WithLabels(webgame.GetLabels()).
+		WithAnnotations(labels.Merge(config.Annotations, canary.CanaryAnnotations(weight))).
+		WithOwnerReferences(ownerReference(webgame)).
+		WithSpec(ingressSpec(webgame, canaryName(webgame), webgame.Spec.Networking.ServerPort, gameRoute, config, tlsSecret))
+	res, err := r.applyChild(ctx, webgame, &canaryIngress, configuration, status)
+	if err != nil {
+		return err
+	}
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("canary ingress changed", "res", res, "weight", weight)
+		r.recordChildChange(webgame, &canaryIngress, res)
+	}
+	return nil
+}
+
+// reconcileIngressObject creates or updates an extra object of the ingress dialect, owned by the webgame.
+func (r *WebGameReconciler) reconcileIngressObject(ctx context.Context, webgame *webgamev2.WebGame, desired *unstructured.Unstructured) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(desired.GroupVersionKind())
+	obj.SetNamespace(desired.GetNamespace())
+	obj.SetName(desired.GetName())
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, obj, func() error {
+		obj.SetLabels(labels.Merge(obj.GetLabels(), webgame.GetLabels()))
+		obj.Object["spec"] = desired.Object["spec"]
+		return controllerutil.SetControllerReference(webgame, obj, r.Scheme)
+	})
+	if meta.IsNoMatchError(err) {
+		return fmt.Errorf("%s is not served by the cluster, install it or use another ingress dialect: %w", desired.GroupVersionKind().Kind, err)
+	}
+	if err != nil {
+		return err
+	}
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("ingress object changed", "kind", desired.GetKind(), "name", desired.GetName(), "res", res)
+		r.recordChildChange(webgame, obj, res)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// Steps of the reconcile timed by reconcileStepDuration.
+const (
+	stepDeployment = "deployment"
+	stepService    = "service"
+	stepIngress    = "ingress"
+	stepHTTPRoute  = "httproute"
+	stepStatic     = "static"
+	stepStatus     = "status"
+)
+
+// collectTimeout bounds the cache reads of a scrape.
+const collectTimeout = 5 * time.Second
+
+var (
+	// reconcileStepDuration times the steps of the reconcile.
+	reconcileStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "webgame_reconcile_step_duration_seconds",
+		Help:    "Duration of the steps of the WebGame reconcile: deployment, service, ingress, httproute, static and status.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"step"})
+
+	// timeToReady observes how long games take to become ready after their spec changed.
+	timeToReady = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "webgame_time_to_ready_seconds",
+		Help:    "Time from a change of the WebGame spec, or its creation, until the game is Ready.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"namespace", "game_type"})
+
+	// idleTransitions counts the games scaled to zero and woken by the idle policy.
+	idleTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "webgame_idle_transitions_total",
+		Help: "Number of games scaled to zero (transition idle) and woken (transition wake) by the idle policy.",
+	}, []string{"namespace", "game_type", "transition"})
+
+	// wakeDuration observes how long the activator held requests while games were woken.
+	wakeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "webgame_activator_wake_duration_seconds",
+		Help:    "Time the activator held a request until the woken game had an available replica.",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"namespace", "game_type"})
+)
+
+var (
+	gamesDesc = prometheus.NewDesc("webgame_games",
+		"Number of WebGames by namespace, game type and phase.",
+		[]string{"namespace", "game_type", "phase"}, nil)
+	desiredReplicasDesc = prometheus.NewDesc("webgame_replicas_desired",
+		"Replicas of the Deployment serving the game, the shared pool for static games.",
+		[]string{"namespace", "name", "game_type"}, nil)
+	readyReplicasDesc = prometheus.NewDesc("webgame_replicas_ready",
+		"Ready replicas of the Deployment serving the game, the shared pool for static games.",
+		[]string{"namespace", "name", "game_type"}, nil)
+	readyDesc = prometheus.NewDesc("webgame_ready",
+		"Whether the Ready condition of the game is True.",
+		[]string{"namespace", "name", "game_type"}, nil)
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcileStepDuration, timeToReady, idleTransitions, wakeDuration)
+}
+
+// observeStep records the duration of a reconcile step started at start.
+func observeStep(step string, start time.Time) {
+	reconcileStepDuration.WithLabelValues(step).Observe(time.Since(start).Seconds())
+}
+
+// fleetCollector reports the WebGames and the replicas of their Deployments, read from the manager cache
+// on every scrape so deleted games have no stale series.
+type fleetCollector struct {
+	reader client.Reader
+}
+
+// Describe implements prometheus.Collector.
+func (c *fleetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- gamesDesc
+	ch <- desiredReplicasDesc
+	ch <- readyReplicasDesc
+	ch <- readyDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *fleetCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	var webgames webgamev2.WebGameList
+	if err := c.reader.List(ctx, &webgames); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list webgames for metrics")
+		return
+	}
+
+	type group struct{ namespace, gameType, phase string }
+	games := map[group]int{}
+	for i := range webgames.Items {
+		webgame := &webgames.Items[i]
+		namespace, name, gameType := webgame.GetNamespace(), webgame.GetName(), webgame.Spec.GameType
+		games[group{namespace, gameType, string(webgame.Status.Phase)}]++
+
+		ready := 0.0
+		if meta.IsStatusConditionTrue(webgame.Status.Conditions, webgamev2.ConditionReady) {
+			ready = 1
+		}
+		ch <- prometheus.MustNewConstMetric(readyDesc, prometheus.GaugeValue, ready, namespace, name, gameType)
+
+		key := types.NamespacedName{Namespace: namespace, Name: name}
+		if webgame.Status.Static != nil {
+			key.Name = webgame.Status.Static.Pool
+		}
+		var deployment appsv1.Deployment
+		if err := c.reader.Get(ctx, key, &deployment); err != nil {
+			continue
+		}
+		var desired int32 = 1
+		if deployment.Spec.Replicas != nil {
+			desired = *deployment.Spec.Replicas
+		}
+		ch <- prometheus.MustNewConstMetric(desiredReplicasDesc, prometheus.GaugeValue, float64(desired), namespace, name, gameType)
+		ch <- prometheus.MustNewConstMetric(readyReplicasDesc, prometheus.GaugeValue, float64(deployment.Status.ReadyReplicas), namespace, name, gameType)
+	}
+	for g, count := range games {
+		ch <- prometheus.MustNewConstMetric(gamesDesc, prometheus.GaugeValue, float64(count), g.namespace, g.gameType, g.phase)
+	}
+}
+
+// readyTimer remembers when the spec of the games changed, to observe their time to ready.
+// It is kept in memory, changes made before the manager started are not observed.
+type readyTimer struct {
+	mu      sync.Mutex
+	changes map[types.NamespacedName]time.Time
+}
+
+// specChanged records that the spec of a game changed, the first time a generation is seen unobserved.
+func (t *readyTimer) specChanged(key types.NamespacedName, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.changes == nil {
+		t.changes = map[types.NamespacedName]time.Time{}
+	}
+	if _, ok := t.changes[key]; !ok {
+		t.changes[key] = now
+	}
+}
+
+// ready observes the time to ready of a game whose spec changed, and forgets the change.
+func (t *readyTimer) ready(webgame *webgamev2.WebGame, now time.Time) {
+	key := client.ObjectKeyFromObject(webgame)
+	t.mu.Lock()
+	changed, ok := t.changes[key]
+	delete(t.changes, key)
+	t.mu.Unlock()
+	if ok {
+		timeToReady.WithLabelValues(webgame.GetNamespace(), webgame.Spec.GameType).Observe(now.Sub(changed).Seconds())
+	}
+}
+
+// forget drops the change of a deleted game.
+func (t *readyTimer) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.changes, key)
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// Condition reasons of the ContainersHealthy condition.
+const (
+	ReasonProbesPassing         = "ProbesPassing"
+	ReasonStartupProbeFailing   = "StartupProbeFailing"
+	ReasonReadinessProbeFailing = "ReadinessProbeFailing"
+	ReasonCrashLooping          = "CrashLooping"
+)
+
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// defaultProbe returns the probe of the index page on the server port, or the override with the same handler
+// when it has none. Fields defaulted by the API server are set explicitly, so the deployment is not updated
+// on every reconcile.
+func defaultProbe(webgame *webgamev2.WebGame, override *corev1.Probe, periodSeconds, failureThreshold int32) *corev1.Probe {
+	probe := &corev1.Probe{PeriodSeconds: periodSeconds, FailureThreshold: failureThreshold}
+	if override != nil {
+		probe = override.DeepCopy()
+	}
+	handler := &probe.ProbeHandler
+	if handler.HTTPGet == nil && handler.TCPSocket == nil && handler.Exec == nil && handler.GRPC == nil {
+		handler.HTTPGet = &corev1.HTTPGetAction{
+			Path: webgame.Spec.Routing.IndexPage,
+			Port: intstr.FromString("web"),
+		}
+	}
+	if handler.HTTPGet != nil && handler.HTTPGet.Scheme == "" {
+		handler.HTTPGet.Scheme = corev1.URISchemeHTTP
+	}
+	if probe.TimeoutSeconds == 0 {
+		probe.TimeoutSeconds = 1
+	}
+	if probe.PeriodSeconds == 0 {
+		probe.PeriodSeconds = 10
+	}
+	if probe.SuccessThreshold == 0 {
+		probe.SuccessThreshold = 1
+	}
+	if probe.FailureThreshold == 0 {
+		probe.FailureThreshold = 3
+	}
+	return probe
+}
+
+// setProbes sets the readiness, liveness and startup probes of the game container.
+// The startup probe gives the game five minutes to serve its index page before liveness takes over.
+func setProbes(webgame *webgamev2.WebGame, container *corev1ac.ContainerApplyConfiguration) {
+	probes := webgame.Spec.Container.Probes
+	if probes == nil {
+		probes = &webgamev2.ProbesSpec{}
+	}
+	container.WithReadinessProbe(probeApplyConfiguration(defaultProbe(webgame, probes.Readiness, 10, 3)))
+	container.WithLivenessProbe(probeApplyConfiguration(defaultProbe(webgame, probes.Liveness, 10, 3)))
+	container.WithStartupProbe(probeApplyConfiguration(defaultProbe(webgame, probes.Startup, 10, 30)))
+}
+
+// probeApplyConfiguration converts a probe to its apply configuration, which has the same JSON fields.
+func probeApplyConfiguration(probe *corev1.Probe) *corev1ac.ProbeApplyConfiguration {
+	configuration := &corev1ac.ProbeApplyConfiguration{}
+	// a probe always encodes, and decodes into its apply configuration
+	data, _ := json.Marshal(probe)
+	_ = json.Unmarshal(data, configuration)
+	return configuration
+}
+
+// checkProbes sets the ContainersHealthy condition from the game pods. A running container that is not ready
+// only counts as failing once its readiness probe had the time to fail, the returned duration is when to check again.
+func (r *WebGameReconciler) checkProbes(ctx context.Context, webgame *webgamev2.WebGame, container *corev1.Container, selector map[string]string, status *webgamev2.WebGameStatus) (time.Duration, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(webgame.GetNamespace()), client.MatchingLabels(selector)); err != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionContainersHealthy, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return 0, err
+	}
+
+	readiness := container.ReadinessProbe
+	grace := time.Duration(readiness.InitialDelaySeconds+readiness.PeriodSeconds*readiness.FailureThreshold) * time.Second
+
+	var (
+		running int
+		requeue time.Duration
+	)
+	for _, pod := range pods.Items {
+		if !pod.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != container.Name {
+				continue
+			}
+			switch {
+			case cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff":
+				setCondition(status, webgame.GetGeneration(), webgamev2.ConditionContainersHealthy, metav1.ConditionFalse, ReasonCrashLooping,
+					fmt.Sprintf("pod %s restarted %d times: %s", pod.GetName(), cs.RestartCount, cs.State.Waiting.Message))
+				return 0, nil
+			case cs.State.Running == nil:
+				continue
+			case cs.Started != nil && !*cs.Started:
+				setCondition(status, webgame.GetGeneration(), webgamev2.ConditionContainersHealthy, metav1.ConditionFalse, ReasonStartupProbeFailing,
+					fmt.Sprintf("pod %s has not passed its startup probe yet", pod.GetName()))
+				return 0, nil
+			case !cs.Ready:
+				if wait := grace - time.Since(cs.State.Running.StartedAt.Time); wait > 0 {
+					if requeue == 0 || wait < requeue {
+						requeue = wait
+					}
+					continue
+				}
+				setCondition(status, webgame.GetGeneration(), webgamev2.ConditionContainersHealthy, metav1.ConditionFalse, ReasonReadinessProbeFailing,
+					fmt.Sprintf("pod %s is failing its readiness probe", pod.GetName()))
+				return 0, nil
+			}
+			running++
+		}
+	}
+
+	if running == 0 {
+		meta.RemoveStatusCondition(&status.Conditions, webgamev2.ConditionContainersHealthy)
+		return requeue, nil
+	}
+	setCondition(status, webgame.GetGeneration(), webgamev2.ConditionContainersHealthy, metav1.ConditionTrue, ReasonProbesPassing, fmt.Sprintf("%d running pods are ready", running))
+	return requeue, nil
+}
+
+// podToWebGame maps a game pod to its webgame, named by the instance label.
+func podToWebGame(_ context.Context, obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()["instance"]
+	if _, game := obj.GetLabels()["gameType"]; !ok || !game {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}}}
+}
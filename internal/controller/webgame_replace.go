@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// ReasonReplacing is the reason of the DeploymentReady condition while a deployment is replaced.
+const ReasonReplacing = "Replacing"
+
+// Event reasons of the replacement of the game deployment.
+const (
+	EventReasonReplacing = "Replacing"
+	EventReasonReplaced  = "Replaced"
+)
+
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=deletecollection
+
+// selectorChanged reports whether the selector of an existing deployment differs from selector.
+func selectorChanged(deployment *appsv1.Deployment, selector map[string]string) bool {
+	if deployment.GetUID() == "" || deployment.Spec.Selector == nil {
+		return false
+	}
+	return !equality.Semantic.DeepEqual(deployment.Spec.Selector.MatchLabels, selector)
+}
+
+// replaceDeployment deletes a deployment whose selector no longer matches the game, the selector of a deployment
+// being immutable, so it is created again with the new one. The replica sets of the deployment are orphaned and
+// keep serving the game until the new deployment is available, a deployment replacing one which is still being
+// replaced never served the game and is deleted with its pods.
+func (r *WebGameReconciler) replaceDeployment(ctx context.Context, webgame *webgamev2.WebGame, deployment *appsv1.Deployment, selector map[string]string, status *webgamev2.WebGameStatus) error {
+	from := labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels).String()
+	setCondition(status, webgame.GetGeneration(), webgamev2.ConditionDeploymentReady, metav1.ConditionFalse, ReasonReplacing,
+		fmt.Sprintf("selector changed from %s to %s, replacing the deployment", from, labels.SelectorFromSet(selector).String()))
+	if !deployment.GetDeletionTimestamp().IsZero() {
+		return nil
+	}
+
+	propagation := metav1.DeletePropagationOrphan
+	if status.ReplacedSelector == nil {
+		status.ReplacedSelector = deployment.Spec.Selector.MatchLabels
+	} else {
+		propagation = metav1.DeletePropagationBackground
+	}
+	if err := r.Delete(ctx, deployment, client.Preconditions{UID: &deployment.UID}, client.PropagationPolicy(propagation)); client.IgnoreNotFound(err) != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionDeploymentReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return err
+	}
+	log.FromContext(ctx).Info("deployment selector changed, replacing the deployment", "selector", from)
+	r.Recorder.Eventf(webgame, corev1.EventTypeNormal, EventReasonReplacing, "Deployment %s selector %s changed, replacing it", deployment.GetName(), from)
+	return nil
+}
+
+// serviceSelector returns the selector of the pods the game service sends requests to: the pods of the replaced
+// deployment until the new one is available.
+func serviceSelector(selector map[string]string, status *webgamev2.WebGameStatus) map[string]string {
+	if status.ReplacedSelector != nil && !meta.IsStatusConditionTrue(status.Conditions, webgamev2.ConditionDeploymentReady) {
+		return status.ReplacedSelector
+	}
+	return selector
+}
+
+// finishReplacement deletes the replica sets of the replaced deployment once the game service selects the pods
+// of the new one. The replica sets are adopted by the new deployment when the selector was changed back.
+func (r *WebGameReconciler) finishReplacement(ctx context.Context, webgame *webgamev2.WebGame, service *corev1.Service, selector map[string]string, status *webgamev2.WebGameStatus) error {
+	replaced := status.ReplacedSelector
+	switch {
+	case replaced == nil, !equality.Semantic.DeepEqual(service.Spec.Selector, selector):
+		return nil
+	case equality.Semantic.DeepEqual(selector, replaced):
+		status.ReplacedSelector = nil
+		return nil
+	}
+	err := r.DeleteAllOf(ctx, &appsv1.ReplicaSet{},
+		client.InNamespace(webgame.GetNamespace()),
+		client.MatchingLabels(replaced),
+		client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil {
+		return err
+	}
+	status.ReplacedSelector = nil
+	log.FromContext(ctx).Info("deployment replaced")
+	r.Recorder.Eventf(webgame, corev1.EventTypeNormal, EventReasonReplaced, "Service %s selects the new pods, replica sets of %s deleted",
+		service.GetName(), labels.SelectorFromSet(replaced).String())
+	return nil
+}
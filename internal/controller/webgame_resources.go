@@ -0,0 +1,45 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/config"
+)
+
+// resolveResources returns the requests and limits of the game container: the size preset,
+// overridden per resource by spec.container.resources. A preset limit lower than an explicit
+// request is raised to the request, so the pod stays valid.
+func resolveResources(cfg *config.Config, spec *webgamev2.ContainerSpec) (corev1.ResourceRequirements, error) {
+	var resources corev1.ResourceRequirements
+	if spec.Size != "" {
+		size, err := cfg.Size(string(spec.Size))
+		if err != nil {
+			return resources, err
+		}
+		resources = size
+	}
+
+	for name, quantity := range spec.Resources.Requests {
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		resources.Requests[name] = quantity.DeepCopy()
+	}
+	for name, quantity := range spec.Resources.Limits {
+		if resources.Limits == nil {
+			resources.Limits = corev1.ResourceList{}
+		}
+		resources.Limits[name] = quantity.DeepCopy()
+	}
+	for name, request := range spec.Resources.Requests {
+		if _, explicit := spec.Resources.Limits[name]; explicit {
+			continue
+		}
+		if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			resources.Limits[name] = request.DeepCopy()
+		}
+	}
+	resources.Claims = spec.Resources.Claims
+	return resources, nil
+}
@@ -0,0 +1,372 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// Condition reasons of the RolloutComplete condition.
+const (
+	ReasonRolloutPaused  = "RolloutPaused"
+	ReasonRolloutAborted = "RolloutAborted"
+)
+
+// Reasons a canary rollout is aborted for, reported in the rollout status.
+const (
+	abortManual            = "ManualAbort"
+	abortImageReverted     = "ImageReverted"
+	abortCanaryUnavailable = "CanaryUnavailable"
+	abortAnalysisFailed    = "AnalysisFailed"
+)
+
+const (
+	defaultCanaryCheckInterval = 30 * time.Second
+	defaultCanaryFailureLimit  = 3
+	// canaryCheckTimeout bounds a check, which runs inside Reconcile and holds a worker
+	canaryCheckTimeout = 2 * time.Second
+)
+
+// canaryClient checks the canaries, a redirect is a response of the canary and is not followed.
+var canaryClient = &http.Client{
+	Timeout: canaryCheckTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// canaryName returns the name of the canary Deployment, Service and Ingress of the webgame.
+func canaryName(webgame *webgamev2.WebGame) string {
+	return webgame.GetName() + "-canary"
+}
+
+// rolloutActive returns true while the canary of a rollout runs.
+func rolloutActive(rollout *webgamev2.RolloutStatus) bool {
+	if rollout == nil {
+		return false
+	}
+	switch rollout.Phase {
+	case webgamev2.RolloutProgressing, webgamev2.RolloutPaused, webgamev2.RolloutPromoting:
+		return true
+	}
+	return false
+}
+
+// canaryWeight returns the percentage of requests the routes send to the canary, false when no canary runs.
+func canaryWeight(status *webgamev2.WebGameStatus) (int32, bool) {
+	if !rolloutActive(status.Rollout) {
+		return 0, false
+	}
+	return status.Rollout.Weight, true
+}
+
+// stableImage returns the image of the game deployment. With the canary strategy, a new image starts
+// a rollout and the current image is kept until the canary is promoted.
+func (r *WebGameReconciler) stableImage(ctx context.Context, webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) (string, error) {
+	image := webgame.Spec.Container.Image
+	canary := webgame.Spec.Rollout.Canary
+	if canary == nil {
+		status.Rollout = nil
+		meta.RemoveStatusCondition(&status.Conditions, webgamev2.ConditionRolloutComplete)
+		return image, nil
+	}
+	defer setRolloutCondition(webgame, status)
+
+	// the first image is deployed right away
+	var deployment appsv1.Deployment
+	if err := r.Get(ctx, client.ObjectKeyFromObject(webgame), &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			return image, nil
+		}
+		return "", err
+	}
+	current := image
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) != 0 {
+		current = containers[0].Image
+	}
+
+	rollout := status.Rollout
+	switch {
+	case rolloutActive(rollout) && image == rollout.StableImage:
+		abortRollout(status, abortImageReverted, "the image was set back to the stable image")
+	case rolloutActive(rollout) && image != rollout.CanaryImage:
+		// a promotion in progress already runs the canary image
+		stable := rollout.StableImage
+		if rollout.Phase == webgamev2.RolloutPromoting {
+			stable = rollout.CanaryImage
+		}
+		log.FromContext(ctx).Info("canary rollout restarted", "image", image)
+		startRollout(status, stable, image)
+	case rolloutActive(rollout), image == current:
+	case rollout != nil && rollout.Phase == webgamev2.RolloutAborted && rollout.CanaryImage == image &&
+		(rollout.Reason != abortManual || canary.Abort):
+		// an aborted image is only tried again once the abort field is unset
+	default:
+		log.FromContext(ctx).Info("canary rollout started", "image", image)
+		startRollout(status, current, image)
+	}
+
+	switch rollout := status.Rollout; {
+	case rollout == nil:
+		return image, nil
+	case rollout.Phase == webgamev2.RolloutProgressing, rollout.Phase == webgamev2.RolloutPaused:
+		return rollout.StableImage, nil
+	case rollout.Phase == webgamev2.RolloutAborted && rollout.CanaryImage == image:
+		return rollout.StableImage, nil
+	}
+	return image, nil
+}
+
+// startRollout starts the canary rollout of image.
+func startRollout(status *webgamev2.WebGameStatus, stable, image string) {
+	status.Rollout = &webgamev2.RolloutStatus{
+		Phase:       webgamev2.RolloutProgressing,
+		StableImage: stable,
+		CanaryImage: image,
+		Message:     "waiting for the canary to be available",
+	}
+}
+
+// abortRollout sends all traffic back to the stable image, the canary is removed once the routes are updated.
+func abortRollout(status *webgamev2.WebGameStatus, reason, message string) {
+	rollout := status.Rollout
+	rollout.Phase = webgamev2.RolloutAborted
+	rollout.Weight = 0
+	rollout.StepStartTime = nil
+	rollout.Reason = reason
+	rollout.Message = message
+}
+
+// setRolloutCondition sets the RolloutComplete condition from the rollout status.
+func setRolloutCondition(webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) {
+	rollout := status.Rollout
+	switch {
+	case rollout == nil:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionRolloutComplete, metav1.ConditionTrue, ReasonComplete,
+			fmt.Sprintf("image %s is deployed", webgame.Spec.Container.Image))
+	case rollout.Phase == webgamev2.RolloutProgressing, rollout.Phase == webgamev2.RolloutPromoting:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionRolloutComplete, metav1.ConditionFalse, ReasonRollingOut, rollout.Message)
+	case rollout.Phase == webgamev2.RolloutPaused:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionRolloutComplete, metav1.ConditionFalse, ReasonRolloutPaused, rollout.Message)
+	case rollout.Phase == webgamev2.RolloutAborted && rollout.CanaryImage == webgame.Spec.Container.Image:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionRolloutComplete, metav1.ConditionFalse, ReasonRolloutAborted,
+			fmt.Sprintf("rollout of %s aborted: %s", rollout.CanaryImage, rollout.Message))
+	default:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionRolloutComplete, metav1.ConditionTrue, ReasonComplete,
+			fmt.Sprintf("image %s is deployed", webgame.Spec.Container.Image))
+	}
+}
+
+// reconcileCanary runs the canary of the active rollout and moves the rollout through its steps.
+// The returned duration is when the current step or the next check is due.
+func (r *WebGameReconciler) reconcileCanary(ctx context.Context, webgame *webgamev2.WebGame, stable *appsv1.Deployment, resources corev1.ResourceRequirements, status *webgamev2.WebGameStatus) (time.Duration, error) {
+	rollout := status.Rollout
+	if !rolloutActive(rollout) {
+		return 0, nil
+	}
+	defer setRolloutCondition(webgame, status)
+	canary := webgame.Spec.Rollout.Canary
+	logger := log.FromContext(ctx)
+
+	if rollout.Phase == webgamev2.RolloutPromoting {
+		// the canary keeps its traffic until the game deployment runs its image
+		if conditionStatus, _, _ := deploymentCondition(stable); conditionStatus == metav1.ConditionTrue &&
+			stable.Spec.Template.Spec.Containers[0].Image == rollout.CanaryImage {
+			rollout.Phase = webgamev2.RolloutSucceeded
+			rollout.Weight = 0
+			rollout.StepStartTime = nil
+			rollout.Message = fmt.Sprintf("image %s promoted", rollout.CanaryImage)
+			logger.Info("canary promoted", "image", rollout.CanaryImage)
+		}
+		return 0, nil
+	}
+
+	if canary.Abort {
+		abortRollout(status, abortManual, "aborted by spec.rollout.canary.abort")
+		logger.Info("canary rollout aborted", "reason", abortManual)
+		return 0, nil
+	}
+
+	deployment, err := r.reconcileCanaryWorkload(ctx, webgame, rollout.CanaryImage, resources, status)
+	if err != nil {
+		return 0, err
+	}
+
+	switch conditionStatus, reason, message := deploymentCondition(deployment); {
+	case reason == ReasonUnavailable, reason == ReasonProgressDeadlineExceeded:
+		abortRollout(status, abortCanaryUnavailable, message)
+		logger.Info("canary rollout aborted", "reason", abortCanaryUnavailable, "message", message)
+		return 0, nil
+	case conditionStatus != metav1.ConditionTrue:
+		// the canary deployment is watched, its status changes trigger the next reconcile
+		rollout.StepStartTime = nil
+		rollout.Message = fmt.Sprintf("waiting for the canary: %s", message)
+		return 0, nil
+	}
+
+	if canary.Paused {
+		rollout.Phase = webgamev2.RolloutPaused
+		rollout.StepStartTime = nil
+		rollout.Message = fmt.Sprintf("paused at step %d of %d", rollout.Step+1, len(canary.Steps))
+		return 0, nil
+	}
+	rollout.Phase = webgamev2.RolloutProgressing
+
+	var requeue time.Duration
+	if analysis := canary.Analysis; analysis != nil {
+		interval, limit := analysis.Interval.Duration, analysis.FailureLimit
+		if interval <= 0 {
+			interval = defaultCanaryCheckInterval
+		}
+		if limit <= 0 {
+			limit = defaultCanaryFailureLimit
+		}
+		if rollout.LastCheckTime == nil || time.Since(rollout.LastCheckTime.Time) >= interval {
+			now := metav1.Now()
+			rollout.LastCheckTime = &now
+			if err := r.checkCanary(ctx, webgame, analysis); err != nil {
+				rollout.FailedChecks++
+				logger.Info("canary check failed", "failures", rollout.FailedChecks, "error", err.Error())
+				if rollout.FailedChecks >= limit {
+					abortRollout(status, abortAnalysisFailed, fmt.Sprintf("%d checks failed, last one: %s", rollout.FailedChecks, err))
+					return 0, nil
+				}
+			}
+		}
+		requeue = interval - time.Since(rollout.LastCheckTime.Time)
+	}
+
+	steps := canary.Steps
+	if int(rollout.Step) >= len(steps) {
+		rollout.Step = int32(len(steps)) - 1
+	}
+	step := steps[rollout.Step]
+	rollout.Weight = step.Weight
+	if rollout.StepStartTime == nil {
+		now := metav1.Now()
+		rollout.StepStartTime = &now
+	}
+
+	if step.Pause != nil {
+		if wait := step.Pause.Duration - time.Since(rollout.StepStartTime.Time); wait > 0 {
+			rollout.Message = fmt.Sprintf("step %d of %d, %d%% of requests to the canary", rollout.Step+1, len(steps), rollout.Weight)
+			return minRequeue(requeue, wait), nil
+		}
+	}
+
+	// the status update of the next step triggers the next reconcile
+	if int(rollout.Step) == len(steps)-1 {
+		rollout.Phase = webgamev2.RolloutPromoting
+		rollout.Message = fmt.Sprintf("promoting image %s", rollout.CanaryImage)
+		logger.Info("canary steps done, promoting", "image", rollout.CanaryImage)
+		return 0, nil
+	}
+	rollout.Step++
+	rollout.Weight = steps[rollout.Step].Weight
+	rollout.StepStartTime = nil
+	rollout.Message = fmt.Sprintf("step %d of %d, %d%% of requests to the canary", rollout.Step+1, len(steps), rollout.Weight)
+	logger.Info("canary step", "step", rollout.Step+1, "weight", rollout.Weight)
+	return requeue, nil
+}
+
+// reconcileCanaryWorkload creates or updates the canary deployment and service, selected by the canary name
+// as instance so the game service and the disruption budget never select canary pods. A canary deployment
+// with another selector is deleted with its pods, and an empty deployment returned until it is created again.
+func (r *WebGameReconciler) reconcileCanaryWorkload(ctx context.Context, webgame *webgamev2.WebGame, image string, resources corev1.ResourceRequirements, status *webgamev2.WebGameStatus) (*appsv1.Deployment, error) {
+	selector := map[string]string{
+		"gameType": webgame.Spec.GameType,
+		"instance": canaryName(webgame),
+	}
+
+	var replicas int32 = 1
+	if webgame.Spec.Rollout.Canary.Replicas != nil {
+		replicas = *webgame.Spec.Rollout.Canary.Replicas
+	}
+	var deployment appsv1.Deployment
+	if err := r.Get(ctx, client.ObjectKey{Namespace: webgame.GetNamespace(), Name: canaryName(webgame)}, &deployment); client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	if selectorChanged(&deployment, selector) {
+		if err := r.Delete(ctx, &deployment, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		return &appsv1.Deployment{}, nil
+	}
+	res, err := r.applyChild(ctx, webgame, &deployment, gameDeployment(webgame, canaryName(webgame), selector, &replicas, image, resources), status)
+	if err != nil {
+		return nil, err
+	}
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("canary deployment changed", "res", res)
+		r.recordChildChange(webgame, &deployment, res)
+	}
+
+	var service corev1.Service
+	res, err = r.applyChild(ctx, webgame, &service, gameService(webgame, canaryName(webgame), selector, webgame.Spec.Networking.ServerPort), status)
+	if err != nil {
+		return nil, err
+	}
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("canary service changed", "res", res)
+		r.recordChildChange(webgame, &service, res)
+	}
+	return &deployment, nil
+}
+
+// checkCanary requests the analysis path on the canary service, a status below 400 is a success.
+func (r *WebGameReconciler) checkCanary(ctx context.Context, webgame *webgamev2.WebGame, analysis *webgamev2.CanaryAnalysis) error {
+	path := analysis.Path
+	if path == "" {
+		path = webgame.Spec.Routing.IndexPage
+	}
+	url := fmt.Sprintf("http://%s.%s.svc:%d%s", canaryName(webgame), webgame.GetNamespace(), webgame.Spec.Networking.ServerPort, path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := canaryClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// deleteCanary deletes the canary deployment and service, once no route sends requests to them.
+func (r *WebGameReconciler) deleteCanary(ctx context.Context, webgame *webgamev2.WebGame) error {
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &corev1.Service{}} {
+		obj.SetNamespace(webgame.GetNamespace())
+		obj.SetName(canaryName(webgame))
+		if err := r.deleteChild(ctx, webgame, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// minRequeue returns the earliest of the requeue durations, zero meaning no requeue.
+func minRequeue(durations ...time.Duration) time.Duration {
+	var earliest time.Duration
+	for _, d := range durations {
+		if earliest <= 0 || (d > 0 && d < earliest) {
+			earliest = d
+		}
+	}
+	return earliest
+}
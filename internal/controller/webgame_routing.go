@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/config"
+)
+
+// gameRoute is where the routing backends serve the game.
+type gameRoute struct {
+	// Host of the route rules, empty to match any host.
+	Host string
+	// Prefix is the path the game is served under, stripped before requests reach the game.
+	// It is empty when the game is served at the root of its host.
+	Prefix string
+	// KeepPrefix routes requests with the prefix, for backends which strip it themselves like the activator.
+	KeepPrefix bool
+}
+
+// hostTemplateData are the fields of the routing host template.
+type hostTemplateData struct {
+	Name      string
+	Namespace string
+	GameType  string
+	Domain    string
+}
+
+// routeOf returns the route of the webgame for its routing mode.
+func routeOf(routing *config.RoutingConfig, webgame *webgamev2.WebGame) (gameRoute, error) {
+	if webgame.Spec.Routing.Mode != webgamev2.RoutingModeHost {
+		return gameRoute{Prefix: fmt.Sprintf("/%s/%s", webgame.Spec.GameType, webgame.GetName())}, nil
+	}
+
+	tmpl, err := template.New("host").Option("missingkey=error").Parse(routing.HostTemplate)
+	if err != nil {
+		return gameRoute{}, fmt.Errorf("invalid routing host template: %w", err)
+	}
+	var host strings.Builder
+	if err := tmpl.Execute(&host, hostTemplateData{
+		Name:      webgame.GetName(),
+		Namespace: webgame.GetNamespace(),
+		GameType:  webgame.Spec.GameType,
+		Domain:    webgame.Spec.Routing.Domain,
+	}); err != nil {
+		return gameRoute{}, fmt.Errorf("unable to execute routing host template: %w", err)
+	}
+
+	hostname := strings.ToLower(host.String())
+	if msgs := validation.IsDNS1123Subdomain(hostname); len(msgs) != 0 {
+		return gameRoute{}, fmt.Errorf("invalid game host %q: %s", hostname, strings.Join(msgs, ", "))
+	}
+	return gameRoute{Host: hostname}, nil
+}
+
+// addressHost returns the host of the game address, the domain when the route matches any host.
+func (g gameRoute) addressHost(webgame *webgamev2.WebGame) string {
+	if g.Host != "" {
+		return g.Host
+	}
+	return webgame.Spec.Routing.Domain
+}
+
+// gameAddress returns the URL of the index page of the game.
+func (g gameRoute) gameAddress(webgame *webgamev2.WebGame, tls bool) string {
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	index := strings.TrimPrefix(webgame.Spec.Routing.IndexPage, "/")
+	return fmt.Sprintf("%s://%s%s/%s", scheme, g.addressHost(webgame), g.Prefix, index)
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// ReasonInvalidSchedule is the reason of the SpecResolved condition when a schedule cannot be parsed.
+const ReasonInvalidSchedule = "InvalidSchedule"
+
+// EventReasonScheduled is the reason of the events of the schedule changes.
+const EventReasonScheduled = "Scheduled"
+
+// scheduleLookback bounds the search of the last start of a schedule, a schedule which has not
+// started for that long is not active.
+const scheduleLookback = 366 * 24 * time.Hour
+
+// parseSchedule parses the cron expression of a schedule in its time zone.
+func parseSchedule(spec *webgamev2.ScheduleSpec) (cron.Schedule, *time.Location, error) {
+	location, err := time.LoadLocation(spec.TimeZone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("schedule %s: %w", spec.Name, err)
+	}
+	schedule, err := cron.ParseStandard(spec.Schedule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("schedule %s: %w", spec.Name, err)
+	}
+	return schedule, location, nil
+}
+
+// lastStart returns the last time at or before now the schedule matched, false when it did not match within the lookback.
+func lastStart(schedule cron.Schedule, now time.Time) (time.Time, bool) {
+	// the window doubles until it holds a start, so only the few starts within it are walked through
+	for window := time.Minute; window <= scheduleLookback; window *= 2 {
+		start := schedule.Next(now.Add(-window))
+		if start.IsZero() {
+			return time.Time{}, false
+		}
+		if start.After(now) {
+			continue
+		}
+		for next := schedule.Next(start); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+			start = next
+		}
+		return start, true
+	}
+	return time.Time{}, false
+}
+
+// applySchedules sets the replicas of the active schedule on the effective spec, and reports the active schedule
+// and the next transition in status. It returns when the next transition happens.
+func (r *WebGameReconciler) applySchedules(webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) (time.Duration, error) {
+	if len(webgame.Spec.Schedules) == 0 {
+		status.Schedule = nil
+		return 0, nil
+	}
+
+	now := time.Now()
+	var (
+		active       *webgamev2.ScheduleSpec
+		activeSince  time.Time
+		activeEnd    time.Time
+		next         time.Time
+		nextSchedule string
+	)
+	for i := range webgame.Spec.Schedules {
+		spec := &webgame.Spec.Schedules[i]
+		schedule, location, err := parseSchedule(spec)
+		if err != nil {
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionSpecResolved, metav1.ConditionFalse, ReasonInvalidSchedule, err.Error())
+			return 0, err
+		}
+
+		local := now.In(location)
+		if start, ok := lastStart(schedule, local); ok {
+			var end time.Time
+			if spec.Duration != nil {
+				end = start.Add(spec.Duration.Duration)
+			}
+			// of the schedules which have not ended, the last started one is active
+			if (end.IsZero() || now.Before(end)) && (active == nil || !start.Before(activeSince)) {
+				active, activeSince, activeEnd = spec, start, end
+			}
+		}
+		if start := schedule.Next(local); !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next, nextSchedule = start, spec.Name
+		}
+	}
+	if !activeEnd.IsZero() && (next.IsZero() || activeEnd.Before(next)) {
+		next, nextSchedule = activeEnd, ""
+	}
+
+	previous := ""
+	if status.Schedule != nil {
+		previous = status.Schedule.Active
+	}
+	status.Schedule = &webgamev2.ScheduleStatus{NextSchedule: nextSchedule}
+	if !next.IsZero() {
+		status.Schedule.NextTransitionTime = &metav1.Time{Time: next}
+	}
+	if active != nil {
+		replicas := active.Replicas
+		status.Schedule.Active = active.Name
+		status.Schedule.ActiveSince = &metav1.Time{Time: activeSince}
+		status.Schedule.Replicas = &replicas
+		setScheduledReplicas(&webgame.Spec.Scaling, replicas)
+		if status.EffectiveSpec != nil {
+			status.EffectiveSpec.Scaling = *webgame.Spec.Scaling.DeepCopy()
+		}
+	}
+
+	switch {
+	case active != nil && active.Name != previous:
+		r.Recorder.Eventf(webgame, corev1.EventTypeNormal, EventReasonScheduled, "schedule %s started, scaling to %d replicas", active.Name, active.Replicas)
+	case active == nil && previous != "":
+		r.Recorder.Eventf(webgame, corev1.EventTypeNormal, EventReasonScheduled, "schedule %s ended, scaling to spec.scaling", previous)
+	}
+
+	if next.IsZero() {
+		return 0, nil
+	}
+	return next.Sub(now), nil
+}
+
+// setScheduledReplicas sets the replicas of a schedule on scaling. They are the minimum of the autoscaler,
+// which is removed while a schedule stops the game.
+func setScheduledReplicas(scaling *webgamev2.ScalingSpec, replicas int32) {
+	autoscaling := scaling.Autoscaling
+	switch {
+	case autoscaling == nil:
+		scaling.Replicas = &replicas
+	case replicas == 0:
+		scaling.Autoscaling = nil
+		scaling.Replicas = &replicas
+	default:
+		minReplicas := min(replicas, autoscaling.MaxReplicas)
+		autoscaling.MinReplicas = &minReplicas
+	}
+}
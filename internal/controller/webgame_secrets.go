@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+const (
+	// imagePullSecretsIndex indexes webgames by the names of their image pull secrets.
+	imagePullSecretsIndex = "spec.container.imagePullSecrets"
+	// restartedAtAnnotation is set on the pod template to roll out the game pods again,
+	// the same way `kubectl rollout restart` does.
+	restartedAtAnnotation = "webgame.webgame.tech/restartedAt"
+)
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+
+// getSecret reads a secret from the API server. The manager watches and caches only the metadata of the
+// secrets, so it does not hold the content of every secret of the cluster.
+func (r *WebGameReconciler) getSecret(ctx context.Context, key client.ObjectKey, secret *corev1.Secret) error {
+	return r.APIReader.Get(ctx, key, secret)
+}
+
+// secretMetadata returns an empty secret metadata, to read from the cache.
+func secretMetadata() *metav1.PartialObjectMetadata {
+	secret := &metav1.PartialObjectMetadata{}
+	secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	return secret
+}
+
+// indexImagePullSecrets is the index function of imagePullSecretsIndex.
+func indexImagePullSecrets(obj client.Object) []string {
+	webgame := obj.(*webgamev2.WebGame)
+	names := make([]string, 0, len(webgame.Spec.Container.ImagePullSecrets))
+	for _, secret := range webgame.Spec.Container.ImagePullSecrets {
+		names = append(names, secret.Name)
+	}
+	return names
+}
+
+// secretToWebGames maps the metadata of a secret to the webgames which pull images with it, serve its certificate or its bundle.
+func (r *WebGameReconciler) secretToWebGames(ctx context.Context, secret client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+	requests := r.bundleToWebGames(ctx, secret)
+	for _, index := range []string{imagePullSecretsIndex, tlsSecretIndex} {
+		var webgames webgamev2.WebGameList
+		if err := r.List(ctx, &webgames, client.InNamespace(secret.GetNamespace()), client.MatchingFields{index: secret.GetName()}); err != nil {
+			logger.Error(err, "unable to list webgames using secret", "secret", secret.GetName(), "index", index)
+			continue
+		}
+		for i := range webgames.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&webgames.Items[i])})
+		}
+	}
+
+	// the wildcard certificate is copied to every namespace using it
+	if wildcard := r.Config.TLS.WildcardSecret; wildcard != nil && wildcard.Namespace == secret.GetNamespace() && wildcard.Name == secret.GetName() {
+		var webgames webgamev2.WebGameList
+		if err := r.List(ctx, &webgames); err != nil {
+			logger.Error(err, "unable to list webgames using the wildcard certificate")
+			return requests
+		}
+		for i := range webgames.Items {
+			if tls := webgames.Items[i].Spec.Routing.TLS; tls != nil && tls.Mode == webgamev2.TLSModeWildcard {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&webgames.Items[i])})
+			}
+		}
+	}
+	return requests
+}
+
+// checkImagePullSecrets sets the ImagePullSecretsReady condition from the referenced secrets, and reports
+// whether the game pods should be restarted because a secret that was missing has appeared.
+func (r *WebGameReconciler) checkImagePullSecrets(ctx context.Context, webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) (bool, error) {
+	var missing, invalid []string
+	for _, ref := range webgame.Spec.Container.ImagePullSecrets {
+		var secret corev1.Secret
+		if err := r.getSecret(ctx, client.ObjectKey{Namespace: webgame.GetNamespace(), Name: ref.Name}, &secret); err != nil {
+			if errors.IsNotFound(err) {
+				missing = append(missing, ref.Name)
+				continue
+			}
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionImagePullSecretsReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+			return false, err
+		}
+		if secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg {
+			invalid = append(invalid, fmt.Sprintf("%s (%s)", ref.Name, secret.Type))
+		}
+	}
+
+	previous := meta.FindStatusCondition(webgame.Status.Conditions, webgamev2.ConditionImagePullSecretsReady)
+	switch {
+	case len(missing) != 0:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionImagePullSecretsReady, metav1.ConditionFalse, ReasonSecretNotFound,
+			fmt.Sprintf("image pull secrets not found: %s", strings.Join(missing, ", ")))
+	case len(invalid) != 0:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionImagePullSecretsReady, metav1.ConditionFalse, ReasonInvalidSecretType,
+			fmt.Sprintf("image pull secrets must be of type %s or %s: %s", corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg, strings.Join(invalid, ", ")))
+	default:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionImagePullSecretsReady, metav1.ConditionTrue, ReasonSynced,
+			fmt.Sprintf("%d image pull secrets found", len(webgame.Spec.Container.ImagePullSecrets)))
+		return previous != nil && previous.Status == metav1.ConditionFalse && previous.Reason == ReasonSecretNotFound, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,549 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/rand"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+const (
+	// staticPoolLabel selects the pods of a shared nginx Deployment, its value is the name of the pool.
+	staticPoolLabel = "webgame.webgame.tech/static-pool"
+	// staticBundleIndex indexes static webgames by the ConfigMap or the Secret of their bundle, as <kind>/<name>.
+	staticBundleIndex = "spec.source.static.bundle"
+	// configHashAnnotation is set on the pod template of a pool, so the shared pods load a new nginx configuration.
+	configHashAnnotation = "webgame.webgame.tech/config-hash"
+
+	staticConfKey    = "default.conf"
+	staticPortsKey   = "ports.json"
+	staticRoot       = "/srv/games"
+	staticHealthPort = 8080
+)
+
+// Condition reasons of the BundleReady condition.
+const (
+	ReasonBundleFound       = "BundleFound"
+	ReasonBundleNotFound    = "BundleNotFound"
+	ReasonBundleFetchFailed = "BundleFetchFailed"
+)
+
+// fetchScript downloads and extracts the archive bundle of a game into its directory. A failure leaves the
+// directory of the game empty and is written to the termination message of the init container, so the shared
+// pods still start and serve the other games.
+const fetchScript = `fetch() {
+  wget -q -O /tmp/bundle "$WEBGAME_BUNDLE_URL" || return
+  case "${WEBGAME_BUNDLE_URL%%\?*}" in
+  *.zip) unzip -q -o /tmp/bundle ;;
+  *) tar -xzf /tmp/bundle ;;
+  esac
+}
+cd "$WEBGAME_BUNDLE_DIR" || exit 1
+if ! output=$(fetch 2>&1); then
+  echo "${output:-download failed}" > /dev/termination-log
+fi
+rm -f /tmp/bundle
+`
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// staticSource returns the static bundle of the webgame, nil when it runs its own image.
+func staticSource(webgame *webgamev2.WebGame) *webgamev2.StaticSource {
+	if webgame.Spec.Source == nil {
+		return nil
+	}
+	return webgame.Spec.Source.Static
+}
+
+// indexStaticBundle is the index function of staticBundleIndex.
+func indexStaticBundle(obj client.Object) []string {
+	static := staticSource(obj.(*webgamev2.WebGame))
+	switch {
+	case static == nil:
+		return nil
+	case static.ConfigMap != nil:
+		return []string{"ConfigMap/" + static.ConfigMap.Name}
+	case static.Secret != nil:
+		return []string{"Secret/" + static.Secret.Name}
+	}
+	return nil
+}
+
+// bundleToWebGames maps a ConfigMap or a Secret to the static webgames serving it as their bundle.
+func (r *WebGameReconciler) bundleToWebGames(ctx context.Context, obj client.Object) []reconcile.Request {
+	// the secrets are watched as metadata only
+	kind := "Secret"
+	if _, ok := obj.(*corev1.ConfigMap); ok {
+		kind = "ConfigMap"
+	}
+	var webgames webgamev2.WebGameList
+	if err := r.List(ctx, &webgames, client.InNamespace(obj.GetNamespace()), client.MatchingFields{staticBundleIndex: kind + "/" + obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list webgames serving bundle", "kind", kind, "name", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(webgames.Items))
+	for i := range webgames.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&webgames.Items[i])})
+	}
+	return requests
+}
+
+// staticPoolToWebGames maps the Deployment of a pool to the webgames it serves, its owners.
+func staticPoolToWebGames(_ context.Context, obj client.Object) []reconcile.Request {
+	if _, ok := obj.GetLabels()[staticPoolLabel]; !ok {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.APIVersion == webgamev2.GroupVersion.String() && owner.Kind == "WebGame" {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: owner.Name}})
+		}
+	}
+	return requests
+}
+
+// staticPoolName returns the name of the pool serving the static webgame, per namespace or per game type.
+func (r *WebGameReconciler) staticPoolName(webgame *webgamev2.WebGame) string {
+	pool := webgamev2.StaticPool(r.Config.Static.Pool)
+	if static := staticSource(webgame); static != nil && static.Pool != "" {
+		pool = static.Pool
+	}
+	if pool != webgamev2.StaticPoolGameType {
+		return "webgame-static"
+	}
+	// the game type is a label value, which can hold characters a name cannot
+	return "webgame-static-" + strings.ToLower(strings.ReplaceAll(webgame.Spec.GameType, "_", "-"))
+}
+
+// reconcileStatic serves a static webgame from the shared nginx Deployment of its pool: the game Service selects
+// the shared pods on the port of the game, and is routed like the Service of a game running its own image.
+func (r *WebGameReconciler) reconcileStatic(ctx context.Context, webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	generation := webgame.GetGeneration()
+
+	// the children of a game running its own image are removed when it turns static
+	var errs []error
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &autoscalingv2.HorizontalPodAutoscaler{}, &policyv1.PodDisruptionBudget{}} {
+		if err := r.deleteChild(ctx, webgame, obj); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := r.deleteCanary(ctx, webgame); err != nil {
+		errs = append(errs, err)
+	}
+	status.Resources = nil
+	status.Rollout = nil
+	status.DisruptionsAllowed = nil
+	meta.RemoveStatusCondition(&status.Conditions, webgamev2.ConditionContainersHealthy)
+	meta.RemoveStatusCondition(&status.Conditions, webgamev2.ConditionRolloutComplete)
+	setCondition(status, generation, webgamev2.ConditionImagePullSecretsReady, metav1.ConditionTrue, ReasonSynced, "static games are served by the images of the controller configuration")
+
+	if err := r.checkStaticBundle(ctx, webgame, status); err != nil {
+		errs = append(errs, err)
+	}
+
+	// the service selects the shared pods on the port of the game, it waits for the pool
+	pool := r.staticPoolName(webgame)
+	start := time.Now()
+	deployment, ports, res, err := r.reconcileStaticPool(ctx, webgame, pool)
+	observeStep(stepStatic, start)
+	if err != nil {
+		setCondition(status, generation, webgamev2.ConditionDeploymentReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return ctrl.Result{}, kerrors.NewAggregate(append(errs, err))
+	}
+	port := ports[webgame.GetName()]
+	status.Static = &webgamev2.StaticStatus{Pool: pool, Port: port}
+	if err := r.checkBundleFetch(ctx, webgame, deployment, port, status); err != nil {
+		errs = append(errs, err)
+	}
+	status.DeploymentStatus = *deployment.Status.DeepCopy()
+	status.Replicas = deployment.Status.Replicas
+	status.Selector = labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels).String()
+	if res != controllerutil.OperationResultNone {
+		logger.Info("static pool changed", "pool", pool, "res", res)
+		setCondition(status, generation, webgamev2.ConditionDeploymentReady, metav1.ConditionFalse, ReasonRollingOut, fmt.Sprintf("shared deployment %s %s", pool, res))
+	} else {
+		conditionStatus, reason, message := deploymentCondition(deployment)
+		setCondition(status, generation, webgamev2.ConditionDeploymentReady, conditionStatus, reason, fmt.Sprintf("shared deployment %s: %s", pool, message))
+	}
+
+	var service corev1.Service
+	res, err = r.applyChild(ctx, webgame, &service, gameService(webgame, webgame.GetName(), deployment.Spec.Selector.MatchLabels, port), status)
+	if err != nil {
+		setCondition(status, generation, webgamev2.ConditionServiceReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return ctrl.Result{}, kerrors.NewAggregate(append(errs, err))
+	}
+	status.ClusterIP = service.Spec.ClusterIP
+	setCondition(status, generation, webgamev2.ConditionServiceReady, metav1.ConditionTrue, ReasonSynced, fmt.Sprintf("service %s", res))
+	if res != controllerutil.OperationResultNone {
+		logger.Info("service changed", "res", res)
+		r.recordChildChange(webgame, &service, res)
+	}
+
+	// the previous pool keeps serving the game until the service selects the new one
+	if err := r.leaveStaticPool(ctx, webgame, webgame.Status.Static); err != nil {
+		errs = append(errs, err)
+	}
+	if err := r.reconcileRoute(ctx, webgame, &service, status); err != nil {
+		errs = append(errs, err)
+	}
+	return ctrl.Result{}, kerrors.NewAggregate(errs)
+}
+
+// checkStaticBundle sets the BundleReady condition from the ConfigMap or the Secret of the bundle. The shared pods
+// mount them as optional volumes, so a missing bundle only breaks its own game.
+func (r *WebGameReconciler) checkStaticBundle(ctx context.Context, webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) error {
+	static := staticSource(webgame)
+	var (
+		obj        client.Object
+		kind, name string
+	)
+	switch {
+	case static.ConfigMap != nil:
+		obj, kind, name = &corev1.ConfigMap{}, "ConfigMap", static.ConfigMap.Name
+	case static.Secret != nil:
+		obj, kind, name = secretMetadata(), "Secret", static.Secret.Name
+	default:
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionBundleReady, metav1.ConditionTrue, ReasonBundleFound, "archive downloaded when the shared pods start")
+		return nil
+	}
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: webgame.GetNamespace(), Name: name}, obj); err != nil {
+		if errors.IsNotFound(err) {
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionBundleReady, metav1.ConditionFalse, ReasonBundleNotFound, fmt.Sprintf("%s %s not found", kind, name))
+			return nil
+		}
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionBundleReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return err
+	}
+	setCondition(status, webgame.GetGeneration(), webgamev2.ConditionBundleReady, metav1.ConditionTrue, ReasonBundleFound, fmt.Sprintf("%s %s found", kind, name))
+	return nil
+}
+
+// checkBundleFetch sets the BundleReady condition of a game with an archive bundle to False when a shared pod
+// failed to download it, from the termination message of the init container of the game.
+func (r *WebGameReconciler) checkBundleFetch(ctx context.Context, webgame *webgamev2.WebGame, deployment *appsv1.Deployment, port int32, status *webgamev2.WebGameStatus) error {
+	static := staticSource(webgame)
+	if static.ConfigMap != nil || static.Secret != nil {
+		return nil
+	}
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(webgame.GetNamespace()), client.MatchingLabels(deployment.Spec.Selector.MatchLabels)); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("fetch-%d", port)
+	for _, pod := range pods.Items {
+		// pods of a previous archive or port of the game are left out
+		fetch := podContainer(pod.Spec.InitContainers, name)
+		if !pod.GetDeletionTimestamp().IsZero() || !slices.Contains(fetch.Env, corev1.EnvVar{Name: "WEBGAME_BUNDLE_URL", Value: static.ArchiveURL}) {
+			continue
+		}
+		for _, container := range pod.Status.InitContainerStatuses {
+			if container.Name != name || container.State.Terminated == nil || container.State.Terminated.Message == "" {
+				continue
+			}
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionBundleReady, metav1.ConditionFalse, ReasonBundleFetchFailed,
+				fmt.Sprintf("pod %s failed to download %s: %s", pod.GetName(), static.ArchiveURL, strings.TrimSpace(container.State.Terminated.Message)))
+			return nil
+		}
+	}
+	return nil
+}
+
+// leaveStaticPool removes the webgame from the pool which served it before, when that pool no longer serves it.
+func (r *WebGameReconciler) leaveStaticPool(ctx context.Context, webgame *webgamev2.WebGame, previous *webgamev2.StaticStatus) error {
+	if previous == nil || (staticMember(webgame) && r.staticPoolName(webgame) == previous.Pool) {
+		return nil
+	}
+	_, _, _, err := r.reconcileStaticPool(ctx, webgame, previous.Pool)
+	return err
+}
+
+// staticMember returns true when the webgame is a static game which is not being deleted.
+func staticMember(webgame *webgamev2.WebGame) bool {
+	return staticSource(webgame) != nil && webgame.GetDeletionTimestamp().IsZero()
+}
+
+// reconcileStaticPool creates or updates the ConfigMap and the Deployment of a pool from the static webgames it serves,
+// webgame taking the place of its cached version, and returns the Deployment and the port of every game.
+// The pool is owned by its games, and deleted once it serves none.
+func (r *WebGameReconciler) reconcileStaticPool(ctx context.Context, webgame *webgamev2.WebGame, pool string) (*appsv1.Deployment, map[string]int32, controllerutil.OperationResult, error) {
+	var webgames webgamev2.WebGameList
+	if err := r.List(ctx, &webgames, client.InNamespace(webgame.GetNamespace())); err != nil {
+		return nil, nil, controllerutil.OperationResultNone, err
+	}
+	var members []*webgamev2.WebGame
+	for i := range webgames.Items {
+		item := &webgames.Items[i]
+		if item.GetUID() == webgame.GetUID() {
+			continue
+		}
+		if staticMember(item) && r.staticPoolName(item) == pool {
+			// the effective spec of the other games holds the replicas of their schedules
+			if item.Status.EffectiveSpec != nil {
+				item.Spec.Scaling = item.Status.EffectiveSpec.Scaling
+			}
+			members = append(members, item)
+		}
+	}
+	if staticMember(webgame) && r.staticPoolName(webgame) == pool {
+		members = append(members, webgame)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].GetName() < members[j].GetName() })
+
+	var configMap corev1.ConfigMap
+	configMap.SetNamespace(webgame.GetNamespace())
+	configMap.SetName(pool)
+	var deployment appsv1.Deployment
+	deployment.SetNamespace(webgame.GetNamespace())
+	deployment.SetName(pool)
+
+	if len(members) == 0 {
+		for _, obj := range []client.Object{&deployment, &configMap} {
+			if err := r.deleteStaticPoolObject(ctx, obj, pool); err != nil {
+				return nil, nil, controllerutil.OperationResultNone, err
+			}
+		}
+		log.FromContext(ctx).Info("static pool deleted", "pool", pool)
+		return nil, nil, controllerutil.OperationResultNone, nil
+	}
+
+	// the ports are kept in the ConfigMap, a concurrent assignment fails on its resource version
+	var ports map[string]int32
+	_, err := ctrl.CreateOrUpdate(ctx, r.Client, &configMap, func() error {
+		if configMap.GetResourceVersion() != "" && configMap.GetLabels()[staticPoolLabel] != pool {
+			return fmt.Errorf("configmap %s exists and is not a static pool", pool)
+		}
+		ports = assignStaticPorts(configMap.Data[staticPortsKey], members, r.Config.Static.BasePort)
+		data, err := json.Marshal(ports)
+		if err != nil {
+			return err
+		}
+		configMap.SetLabels(labels.Merge(configMap.GetLabels(), map[string]string{staticPoolLabel: pool}))
+		configMap.Data = map[string]string{
+			staticConfKey:  nginxConf(members, ports),
+			staticPortsKey: string(data),
+		}
+		return r.setStaticPoolOwners(&configMap, members)
+	})
+	if err != nil {
+		return nil, nil, controllerutil.OperationResultNone, err
+	}
+
+	resources, err := r.Config.Size(r.Config.Static.Size)
+	if err != nil {
+		return nil, nil, controllerutil.OperationResultNone, err
+	}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &deployment, func() error {
+		if deployment.GetResourceVersion() != "" && deployment.GetLabels()[staticPoolLabel] != pool {
+			return fmt.Errorf("deployment %s exists and is not a static pool", pool)
+		}
+		deployment.SetLabels(labels.Merge(deployment.GetLabels(), map[string]string{staticPoolLabel: pool}))
+		var replicas int32
+		for _, member := range members {
+			if member.Spec.Scaling.Replicas == nil {
+				replicas = max(replicas, 1)
+			} else {
+				replicas = max(replicas, *member.Spec.Scaling.Replicas)
+			}
+		}
+		deployment.Spec.Replicas = &replicas
+		r.setStaticPodTemplate(&deployment, pool, members, ports, configMap.Data[staticConfKey], resources)
+		return r.setStaticPoolOwners(&deployment, members)
+	})
+	if err != nil {
+		return nil, nil, res, err
+	}
+	return &deployment, ports, res, nil
+}
+
+// deleteStaticPoolObject deletes the ConfigMap or the Deployment of a pool, when it exists and is labelled with the pool.
+func (r *WebGameReconciler) deleteStaticPoolObject(ctx context.Context, obj client.Object, pool string) error {
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if obj.GetLabels()[staticPoolLabel] != pool {
+		return nil
+	}
+	if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// setStaticPoolOwners makes the games of a pool the owners of its objects, in place of the previous games.
+func (r *WebGameReconciler) setStaticPoolOwners(obj client.Object, members []*webgamev2.WebGame) error {
+	var owners []metav1.OwnerReference
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.APIVersion != webgamev2.GroupVersion.String() || owner.Kind != "WebGame" {
+			owners = append(owners, owner)
+		}
+	}
+	obj.SetOwnerReferences(owners)
+	for _, member := range members {
+		if err := controllerutil.SetOwnerReference(member, obj, r.Scheme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignStaticPorts returns the ports of the games of a pool: the ports assigned before are kept,
+// new games get the lowest free port above the base port.
+func assignStaticPorts(current string, members []*webgamev2.WebGame, basePort int32) map[string]int32 {
+	var previous map[string]int32
+	_ = json.Unmarshal([]byte(current), &previous)
+
+	ports := make(map[string]int32, len(members))
+	used := make(map[int32]bool, len(members))
+	for _, member := range members {
+		if port, ok := previous[member.GetName()]; ok && port > basePort && !used[port] {
+			ports[member.GetName()] = port
+			used[port] = true
+		}
+	}
+	next := basePort + 1
+	for _, member := range members {
+		if _, ok := ports[member.GetName()]; ok {
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		ports[member.GetName()] = next
+		used[next] = true
+	}
+	return ports
+}
+
+// nginxConf returns the nginx configuration of a pool, a server per game on its port and the health server.
+func nginxConf(members []*webgamev2.WebGame, ports map[string]int32) string {
+	var conf strings.Builder
+	fmt.Fprintf(&conf, "server {\n    listen %d;\n    location = /healthz {\n        access_log off;\n        return 200;\n    }\n}\n", staticHealthPort)
+	for _, member := range members {
+		fmt.Fprintf(&conf, "\n# %s\nserver {\n    listen %d;\n    root %s;\n    index index.html;\n}\n",
+			member.GetName(), ports[member.GetName()], path.Join(staticRoot, member.GetName()))
+	}
+	return conf.String()
+}
+
+// setStaticPodTemplate sets the selector and the pod template of the Deployment of a pool: nginx serves the bundle
+// of every game from its own directory, an archive bundle is downloaded into it by an init container which does
+// not fail the pod when the download fails. Fields defaulted by the API server are set explicitly, so the
+// deployment is not updated on every reconcile.
+func (r *WebGameReconciler) setStaticPodTemplate(deployment *appsv1.Deployment, pool string, members []*webgamev2.WebGame, ports map[string]int32, conf string, resources corev1.ResourceRequirements) {
+	selector := map[string]string{staticPoolLabel: pool}
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+	deployment.Spec.Template.SetLabels(selector)
+
+	hasher := fnv.New32a()
+	hasher.Write([]byte(conf))
+	deployment.Spec.Template.SetAnnotations(labels.Merge(deployment.Spec.Template.GetAnnotations(), map[string]string{
+		configHashAnnotation: rand.SafeEncodeString(fmt.Sprint(hasher.Sum32())),
+	}))
+
+	var (
+		defaultMode    = corev1.ConfigMapVolumeSourceDefaultMode
+		optional       = true
+		spec           = &deployment.Spec.Template.Spec
+		initContainers []corev1.Container
+	)
+	volumes := []corev1.Volume{{
+		Name: "config",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: pool},
+			Items:                []corev1.KeyToPath{{Key: staticConfKey, Path: staticConfKey}},
+			DefaultMode:          &defaultMode,
+		}},
+	}}
+	mounts := []corev1.VolumeMount{{Name: "config", MountPath: "/etc/nginx/conf.d", ReadOnly: true}}
+
+	for _, member := range members {
+		static := member.Spec.Source.Static
+		volume := corev1.Volume{Name: fmt.Sprintf("game-%d", ports[member.GetName()])}
+		dir := path.Join(staticRoot, member.GetName())
+		switch {
+		case static.ConfigMap != nil:
+			volume.ConfigMap = &corev1.ConfigMapVolumeSource{LocalObjectReference: *static.ConfigMap, DefaultMode: &defaultMode, Optional: &optional}
+		case static.Secret != nil:
+			volume.Secret = &corev1.SecretVolumeSource{SecretName: static.Secret.Name, DefaultMode: &defaultMode, Optional: &optional}
+		default:
+			volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+			fetch := podContainer(spec.InitContainers, "fetch-"+strings.TrimPrefix(volume.Name, "game-"))
+			fetch.Image = r.Config.Static.FetchImage
+			fetch.Command = []string{"sh", "-c", fetchScript}
+			fetch.Env = []corev1.EnvVar{
+				{Name: "WEBGAME_BUNDLE_URL", Value: static.ArchiveURL},
+				{Name: "WEBGAME_BUNDLE_DIR", Value: dir},
+			}
+			fetch.VolumeMounts = []corev1.VolumeMount{{Name: volume.Name, MountPath: dir}}
+			initContainers = append(initContainers, fetch)
+		}
+		volumes = append(volumes, volume)
+		mounts = append(mounts, corev1.VolumeMount{Name: volume.Name, MountPath: dir, ReadOnly: true})
+	}
+
+	nginx := podContainer(spec.Containers, "nginx")
+	nginx.Image = r.Config.Static.Image
+	nginx.Resources = resources
+	nginx.Ports = []corev1.ContainerPort{{Name: "health", ContainerPort: staticHealthPort, Protocol: corev1.ProtocolTCP}}
+	nginx.VolumeMounts = mounts
+	nginx.ReadinessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{
+			Path:   "/healthz",
+			Port:   intstr.FromString("health"),
+			Scheme: corev1.URISchemeHTTP,
+		}},
+		TimeoutSeconds:   1,
+		PeriodSeconds:    10,
+		SuccessThreshold: 1,
+		FailureThreshold: 3,
+	}
+
+	spec.InitContainers = initContainers
+	spec.Containers = []corev1.Container{nginx}
+	spec.Volumes = volumes
+}
+
+// podContainer returns the container of the list with the given name, a new one when there is none.
+// The fields defaulted by the API server are kept.
+func podContainer(containers []corev1.Container, name string) corev1.Container {
+	for _, container := range containers {
+		if container.Name == name {
+			return container
+		}
+	}
+	return corev1.Container{
+		Name:                     name,
+		ImagePullPolicy:          corev1.PullIfNotPresent,
+		TerminationMessagePath:   corev1.TerminationMessagePathDefault,
+		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+	}
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+var _ = Describe("Test static bundle downloads", func() {
+	It("report the archive a shared pod failed to download", func() {
+		const url = "https://games.example.com/broken.zip"
+		webgame := &webgamev2.WebGame{}
+		webgame.SetNamespace("games")
+		webgame.SetName("webgame-static-broken")
+		webgame.Spec.Source = &webgamev2.SourceSpec{Static: &webgamev2.StaticSource{ArchiveURL: url}}
+
+		selector := map[string]string{staticPoolLabel: "webgame-static"}
+		deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: selector}}}
+		pod := func(name, url, message string) client.Object {
+			pod := &corev1.Pod{}
+			pod.SetNamespace(webgame.GetNamespace())
+			pod.SetName(name)
+			pod.SetLabels(selector)
+			pod.Spec.InitContainers = []corev1.Container{{Name: "fetch-8081", Env: []corev1.EnvVar{{Name: "WEBGAME_BUNDLE_URL", Value: url}}}}
+			pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+				Name:  "fetch-8081",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed", Message: message}},
+			}}
+			return pod
+		}
+		r := &WebGameReconciler{Scheme: scheme.Scheme}
+
+		// the failure of a previous archive is not reported
+		r.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(pod("webgame-static-old", "https://games.example.com/old.zip", "404 Not Found")).Build()
+		status := &webgamev2.WebGameStatus{}
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionBundleReady, metav1.ConditionTrue, ReasonBundleFound, "archive downloaded when the shared pods start")
+		Expect(r.checkBundleFetch(ctx, webgame, deployment, 8081, status)).Should(Succeed())
+		Expect(meta.IsStatusConditionTrue(status.Conditions, webgamev2.ConditionBundleReady)).Should(BeTrue())
+
+		r.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(pod("webgame-static-new", url, "wget: server returned error: HTTP/1.1 404 Not Found\n")).Build()
+		Expect(r.checkBundleFetch(ctx, webgame, deployment, 8081, status)).Should(Succeed())
+		condition := meta.FindStatusCondition(status.Conditions, webgamev2.ConditionBundleReady)
+		Expect(condition.Status).Should(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).Should(Equal(ReasonBundleFetchFailed))
+		Expect(condition.Message).Should(HaveSuffix("404 Not Found"))
+	})
+})
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+// Condition reasons reported by the webgame controller.
+const (
+	ReasonAvailable                = "Available"
+	ReasonSynced                   = "Synced"
+	ReasonRollingOut               = "RollingOut"
+	ReasonReconciling              = "Reconciling"
+	ReasonUnavailable              = "Unavailable"
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	ReasonReconcileError           = "ReconcileError"
+	ReasonComplete                 = "Complete"
+	ReasonAsExpected               = "AsExpected"
+	ReasonSecretNotFound           = "SecretNotFound"
+	ReasonInvalidSecretType        = "InvalidSecretType"
+	ReasonCertificateNotReady      = "CertificateNotReady"
+	ReasonFeatureUnsupported       = "FeatureUnsupported"
+)
+
+// childConditions are the per-resource conditions that make up the Ready condition, in reconcile order.
+var childConditions = []string{
+	webgamev2.ConditionImagePullSecretsReady,
+	webgamev2.ConditionDeploymentReady,
+	webgamev2.ConditionServiceReady,
+}
+
+// routeConditions are the conditions of the routing backends, the one of the backend in use is required.
+var routeConditions = []string{
+	webgamev2.ConditionIngressReady,
+	webgamev2.ConditionHTTPRouteReady,
+}
+
+// optionalConditions only count towards the Ready condition when they are set.
+var optionalConditions = []string{
+	webgamev2.ConditionSpecResolved,
+	webgamev2.ConditionCertificateReady,
+	webgamev2.ConditionContainersHealthy,
+	webgamev2.ConditionRolloutComplete,
+	webgamev2.ConditionBundleReady,
+}
+
+// setCondition sets a condition on status, keeping the transition time if the status did not change.
+func setCondition(status *webgamev2.WebGameStatus, generation int64, conditionType string, conditionStatus metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// deploymentCondition derives the DeploymentReady condition from the observed deployment status,
+// following the same rules as `kubectl rollout status`.
+func deploymentCondition(deployment *appsv1.Deployment) (metav1.ConditionStatus, string, string) {
+	var desired int32 = 1
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	var progressing *appsv1.DeploymentCondition
+	for i := range deployment.Status.Conditions {
+		if deployment.Status.Conditions[i].Type == appsv1.DeploymentProgressing {
+			progressing = &deployment.Status.Conditions[i]
+		}
+	}
+
+	st := deployment.Status
+	switch {
+	case progressing != nil && progressing.Reason == "ProgressDeadlineExceeded":
+		return metav1.ConditionFalse, ReasonProgressDeadlineExceeded, progressing.Message
+	case st.ObservedGeneration < deployment.Generation:
+		return metav1.ConditionFalse, ReasonRollingOut, "waiting for deployment spec update to be observed"
+	case st.UpdatedReplicas < desired:
+		return metav1.ConditionFalse, ReasonRollingOut, fmt.Sprintf("%d of %d updated replicas", st.UpdatedReplicas, desired)
+	case st.Replicas > st.UpdatedReplicas:
+		return metav1.ConditionFalse, ReasonRollingOut, fmt.Sprintf("%d old replicas are pending termination", st.Replicas-st.UpdatedReplicas)
+	case st.AvailableReplicas < desired:
+		message := fmt.Sprintf("%d of %d updated replicas are available", st.AvailableReplicas, desired)
+		if progressing != nil && progressing.Reason == "NewReplicaSetAvailable" {
+			// the rollout has completed before, so missing replicas mean unhealthy pods
+			return metav1.ConditionFalse, ReasonUnavailable, message
+		}
+		return metav1.ConditionFalse, ReasonRollingOut, message
+	}
+	return metav1.ConditionTrue, ReasonAvailable, fmt.Sprintf("%d of %d replicas are available", st.AvailableReplicas, desired)
+}
+
+// summarize sets the Ready, Progressing and Degraded conditions, the phase, the last error
+// and the observed generation from the child conditions and the reconcile error.
+func summarize(status *webgamev2.WebGameStatus, generation int64, reconcileErr error) {
+	var (
+		notReady    *metav1.Condition
+		progressing *metav1.Condition
+		degraded    *metav1.Condition
+	)
+	// the route condition of the backend in use, IngressReady until a backend has been reconciled
+	route := webgamev2.ConditionIngressReady
+	for _, conditionType := range routeConditions {
+		if meta.FindStatusCondition(status.Conditions, conditionType) != nil {
+			route = conditionType
+		}
+	}
+
+	required := append(append([]string{}, childConditions...), route)
+	for _, conditionType := range append(required, optionalConditions...) {
+		condition := meta.FindStatusCondition(status.Conditions, conditionType)
+		if condition == nil {
+			if slices.Contains(optionalConditions, conditionType) {
+				continue
+			}
+			condition = &metav1.Condition{Type: conditionType, Status: metav1.ConditionUnknown, Reason: ReasonReconciling, Message: "not reconciled yet"}
+		}
+		if condition.Status == metav1.ConditionTrue {
+			continue
+		}
+		if notReady == nil {
+			notReady = condition
+		}
+		switch condition.Reason {
+		case ReasonRollingOut, ReasonReconciling, ReasonCertificateNotReady, ReasonRoutePending, ReasonStartupProbeFailing,
+			ReasonRolloutPaused, ReasonReplacing:
+			if progressing == nil {
+				progressing = condition
+			}
+		case ReasonUnavailable, ReasonProgressDeadlineExceeded, ReasonReconcileError, ReasonSecretNotFound, ReasonInvalidSecretType,
+			ReasonRouteNotAccepted, ReasonRefsNotResolved, ReasonReadinessProbeFailing, ReasonCrashLooping, ReasonRolloutAborted,
+			ReasonImageRequired, ReasonImageNotAllowed, ReasonInstanceLimitExceeded, ReasonBundleNotFound, ReasonBundleFetchFailed, ReasonInvalidSchedule,
+			ReasonSecretConflict:
+			if degraded == nil {
+				degraded = condition
+			}
+		}
+	}
+
+	status.ObservedGeneration = generation
+	status.LastError = ""
+	if reconcileErr != nil {
+		status.LastError = reconcileErr.Error()
+		degraded = &metav1.Condition{Reason: ReasonReconcileError, Message: reconcileErr.Error()}
+	}
+
+	if notReady == nil {
+		setCondition(status, generation, webgamev2.ConditionReady, metav1.ConditionTrue, ReasonAvailable, "game is available")
+	} else {
+		setCondition(status, generation, webgamev2.ConditionReady, metav1.ConditionFalse, notReady.Reason, fmt.Sprintf("%s: %s", notReady.Type, notReady.Message))
+	}
+
+	if progressing == nil {
+		setCondition(status, generation, webgamev2.ConditionProgressing, metav1.ConditionFalse, ReasonComplete, "all resources are up to date")
+	} else {
+		setCondition(status, generation, webgamev2.ConditionProgressing, metav1.ConditionTrue, progressing.Reason, fmt.Sprintf("%s: %s", progressing.Type, progressing.Message))
+	}
+
+	if degraded == nil {
+		setCondition(status, generation, webgamev2.ConditionDegraded, metav1.ConditionFalse, ReasonAsExpected, "game is healthy")
+	} else {
+		setCondition(status, generation, webgamev2.ConditionDegraded, metav1.ConditionTrue, degraded.Reason, degraded.Message)
+	}
+
+	switch {
+	case reconcileErr != nil:
+		status.Phase = webgamev2.PhaseFailed
+	case degraded != nil:
+		status.Phase = webgamev2.PhaseDegraded
+	case notReady == nil:
+		status.Phase = webgamev2.PhaseReady
+	case progressing != nil:
+		status.Phase = webgamev2.PhaseProgressing
+	default:
+		status.Phase = webgamev2.PhasePending
+	}
+}
+
+// syncStatus writes status to the status subresource of the webgame if it changed. The merge patch only
+// holds the status, so it neither conflicts with nor overwrites changes made to the spec in the meantime.
+func (r *WebGameReconciler) syncStatus(ctx context.Context, webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) error {
+	defer observeStep(stepStatus, time.Now())
+	if equality.Semantic.DeepEqual(&webgame.Status, status) {
+		return nil
+	}
+
+	patch := client.MergeFrom(webgame.DeepCopy())
+	webgame.Status = *status
+	if err := r.Status().Patch(ctx, webgame, patch); err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("webgame status synced", "phase", status.Phase)
+	return nil
+}
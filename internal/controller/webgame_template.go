@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/config"
+)
+
+// gameTypeIndex indexes WebGames by game type, to requeue the WebGames of a GameTemplate and count instances.
+const gameTypeIndex = "spec.gameType"
+
+// Condition reasons of the SpecResolved condition.
+const (
+	ReasonResolved              = "Resolved"
+	ReasonImageRequired         = "ImageRequired"
+	ReasonImageNotAllowed       = "ImageNotAllowed"
+	ReasonInstanceLimitExceeded = "InstanceLimitExceeded"
+)
+
+// instanceLimitRequeue is how often a WebGame over the instance limit of its template checks for a free slot.
+const instanceLimitRequeue = time.Minute
+
+// +kubebuilder:rbac:groups=webgame.webgame.tech,resources=gametemplates,verbs=get;list;watch
+
+// indexGameType returns the game type of a webgame.
+func indexGameType(obj client.Object) []string {
+	webgame, ok := obj.(*webgamev2.WebGame)
+	if !ok || webgame.Spec.GameType == "" {
+		return nil
+	}
+	return []string{webgame.Spec.GameType}
+}
+
+// templateToWebGames maps a GameTemplate to the WebGames of its game type.
+func (r *WebGameReconciler) templateToWebGames(ctx context.Context, obj client.Object) []reconcile.Request {
+	var webgames webgamev2.WebGameList
+	if err := r.List(ctx, &webgames, client.MatchingFields{gameTypeIndex: obj.GetName()}); err != nil {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(webgames.Items))
+	for _, webgame := range webgames.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&webgame)})
+	}
+	return requests
+}
+
+// resolveSpec replaces the spec of the webgame with its effective spec: the WebGame spec merged over the
+// GameTemplate of its game type and the cluster defaults, and returns the configuration with the size presets
+// of the template. The webgame is never updated with the effective spec, its status only is written.
+func (r *WebGameReconciler) resolveSpec(ctx context.Context, webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) (config.Config, time.Duration, error) {
+	cfg := r.Config
+	var template webgamev2.GameTemplate
+	if err := r.Get(ctx, client.ObjectKey{Name: webgame.Spec.GameType}, &template); err != nil {
+		if !errors.IsNotFound(err) {
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionSpecResolved, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+			return cfg, 0, err
+		}
+		template = webgamev2.GameTemplate{}
+		status.Template = ""
+	} else {
+		status.Template = template.GetName()
+	}
+
+	spec := &webgame.Spec
+	defaults := template.Spec.Defaults
+	if spec.Container.Image == "" && staticSource(webgame) == nil {
+		spec.Container.Image = defaults.Image
+	}
+	if len(spec.Container.ImagePullSecrets) == 0 {
+		spec.Container.ImagePullSecrets = defaults.ImagePullSecrets
+	}
+	if spec.Container.Size == "" {
+		spec.Container.Size = defaults.Size
+	}
+	if spec.Networking.ServerPort == 0 {
+		spec.Networking.ServerPort = defaults.ServerPort
+	}
+	if spec.Networking.ServerPort == 0 {
+		spec.Networking.ServerPort = 80
+	}
+	if spec.Networking.IngressClass == "" {
+		spec.Networking.IngressClass = defaults.IngressClass
+	}
+	if spec.Routing.IndexPage == "" {
+		spec.Routing.IndexPage = defaults.IndexPage
+	}
+	if spec.Routing.IndexPage == "" {
+		spec.Routing.IndexPage = "/"
+	}
+	status.EffectiveSpec = spec.DeepCopy()
+
+	if len(template.Spec.Sizes) != 0 {
+		cfg.Sizes = make(map[string]corev1.ResourceRequirements, len(r.Config.Sizes)+len(template.Spec.Sizes))
+		for name, size := range r.Config.Sizes {
+			cfg.Sizes[name] = size
+		}
+		for name, size := range template.Spec.Sizes {
+			cfg.Sizes[string(name)] = size
+		}
+	}
+
+	switch {
+	case staticSource(webgame) != nil:
+		// static games are served by the nginx image of the controller configuration
+	case spec.Container.Image == "":
+		err := fmt.Errorf("spec.container.image is required, the game type %q has no GameTemplate image", spec.GameType)
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionSpecResolved, metav1.ConditionFalse, ReasonImageRequired, err.Error())
+		return cfg, 0, err
+	case len(template.Spec.AllowedImageRepositories) != 0 && !imageAllowed(spec.Container.Image, template.Spec.AllowedImageRepositories):
+		repositories := template.Spec.AllowedImageRepositories
+		err := fmt.Errorf("image %s is not in the allowed repositories of the game type %q: %s", spec.Container.Image, spec.GameType, strings.Join(repositories, ", "))
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionSpecResolved, metav1.ConditionFalse, ReasonImageNotAllowed, err.Error())
+		return cfg, 0, err
+	}
+
+	message, err := r.checkInstanceLimits(ctx, webgame, &template)
+	if err != nil {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionSpecResolved, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+		return cfg, 0, err
+	}
+	if message != "" {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionSpecResolved, metav1.ConditionFalse, ReasonInstanceLimitExceeded, message)
+		return cfg, instanceLimitRequeue, nil
+	}
+
+	message = "no GameTemplate for the game type"
+	if status.Template != "" {
+		message = fmt.Sprintf("merged over GameTemplate %s", status.Template)
+	}
+	setCondition(status, webgame.GetGeneration(), webgamev2.ConditionSpecResolved, metav1.ConditionTrue, ReasonResolved, message)
+	return cfg, 0, nil
+}
+
+// imageAllowed returns true when image is in one of the repositories.
+func imageAllowed(image string, repositories []string) bool {
+	for _, repository := range repositories {
+		repository = strings.TrimSuffix(repository, "/")
+		if image == repository || strings.HasPrefix(image, repository+"/") ||
+			strings.HasPrefix(image, repository+":") || strings.HasPrefix(image, repository+"@") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInstanceLimits returns why the webgame is over the instance limits of its template, empty when it is not.
+// The oldest WebGames of the game type are within the limits.
+func (r *WebGameReconciler) checkInstanceLimits(ctx context.Context, webgame *webgamev2.WebGame, template *webgamev2.GameTemplate) (string, error) {
+	if template.Spec.MaxInstances == nil && template.Spec.MaxInstancesPerNamespace == nil {
+		return "", nil
+	}
+	var webgames webgamev2.WebGameList
+	if err := r.List(ctx, &webgames, client.MatchingFields{gameTypeIndex: webgame.Spec.GameType}); err != nil {
+		return "", err
+	}
+	instances := webgames.Items[:0]
+	for _, item := range webgames.Items {
+		if item.GetDeletionTimestamp().IsZero() {
+			instances = append(instances, item)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		a, b := instances[i].GetCreationTimestamp(), instances[j].GetCreationTimestamp()
+		if !a.Equal(&b) {
+			return a.Before(&b)
+		}
+		return client.ObjectKeyFromObject(&instances[i]).String() < client.ObjectKeyFromObject(&instances[j]).String()
+	})
+
+	var position, namespacePosition int32
+	for _, item := range instances {
+		if item.GetUID() == webgame.GetUID() {
+			break
+		}
+		position++
+		if item.GetNamespace() == webgame.GetNamespace() {
+			namespacePosition++
+		}
+	}
+	if limit := template.Spec.MaxInstances; limit != nil && position >= *limit {
+		return fmt.Sprintf("the game type %q is limited to %d instances in the cluster", webgame.Spec.GameType, *limit), nil
+	}
+	if limit := template.Spec.MaxInstancesPerNamespace; limit != nil && namespacePosition >= *limit {
+		return fmt.Sprintf("the game type %q is limited to %d instances in a namespace", webgame.Spec.GameType, *limit), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+)
+
+const (
+	// tlsSecretIndex indexes webgames by the name of their TLS secret.
+	tlsSecretIndex = "spec.routing.tls.secretName"
+
+	certManagerIssuerAnnotation        = "cert-manager.io/issuer"
+	certManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+
+	// wildcardCopyLabel marks the copies of the wildcard certificate, the only secrets the controller
+	// overwrites with the wildcard certificate and deletes once no game of their namespace uses them.
+	wildcardCopyLabel = "webgame.webgame.tech/wildcard-copy"
+)
+
+// ReasonSecretConflict is the reason of the CertificateReady condition when a secret which is not a copy
+// of the wildcard certificate has its name.
+const ReasonSecretConflict = "SecretConflict"
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=create;update;patch;delete
+
+// indexTLSSecret is the index function of tlsSecretIndex.
+func (r *WebGameReconciler) indexTLSSecret(obj client.Object) []string {
+	webgame := obj.(*webgamev2.WebGame)
+	tls := webgame.Spec.Routing.TLS
+	switch {
+	case tls == nil:
+		return nil
+	case tls.Mode == webgamev2.TLSModeWildcard && r.Config.TLS.WildcardSecret != nil:
+		return []string{r.Config.TLS.WildcardSecret.Name}
+	case tls.SecretName != "":
+		return []string{tls.SecretName}
+	}
+	return nil
+}
+
+// tlsSecretName returns the secret holding the certificate of the game address, empty when TLS is disabled,
+// and sets the CertificateReady condition. The wildcard certificate is copied to the webgame namespace first.
+func (r *WebGameReconciler) tlsSecretName(ctx context.Context, webgame *webgamev2.WebGame, status *webgamev2.WebGameStatus) (string, error) {
+	tls := webgame.Spec.Routing.TLS
+	if tls == nil || tls.Mode != webgamev2.TLSModeWildcard {
+		if err := r.releaseWildcardCopy(ctx, webgame); err != nil {
+			return "", err
+		}
+	}
+	if tls == nil {
+		meta.RemoveStatusCondition(&status.Conditions, webgamev2.ConditionCertificateReady)
+		return "", nil
+	}
+
+	name := tls.SecretName
+	switch tls.Mode {
+	case webgamev2.TLSModeCertManager:
+		if name == "" {
+			name = webgame.GetName() + "-tls"
+		}
+	case webgamev2.TLSModeWildcard:
+		wildcard := r.Config.TLS.WildcardSecret
+		if wildcard == nil {
+			err := fmt.Errorf("tls mode %s requires a wildcard secret in the controller configuration", tls.Mode)
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionCertificateReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+			return "", err
+		}
+		name = wildcard.Name
+		if wildcard.Namespace != "" && wildcard.Namespace != webgame.GetNamespace() {
+			copied, err := r.copyWildcardSecret(ctx, wildcard, webgame.GetNamespace())
+			if err != nil {
+				setCondition(status, webgame.GetGeneration(), webgamev2.ConditionCertificateReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+				return "", err
+			}
+			if !copied {
+				err := fmt.Errorf("secret %s is not a copy of the wildcard certificate, rename it or remove it", name)
+				setCondition(status, webgame.GetGeneration(), webgamev2.ConditionCertificateReady, metav1.ConditionFalse, ReasonSecretConflict, err.Error())
+				return "", err
+			}
+		}
+	}
+
+	var secret corev1.Secret
+	if err := r.getSecret(ctx, client.ObjectKey{Namespace: webgame.GetNamespace(), Name: name}, &secret); err != nil {
+		if !errors.IsNotFound(err) {
+			setCondition(status, webgame.GetGeneration(), webgamev2.ConditionCertificateReady, metav1.ConditionFalse, ReasonReconcileError, err.Error())
+			return "", err
+		}
+		message := fmt.Sprintf("secret %s not found", name)
+		if tls.Mode == webgamev2.TLSModeCertManager {
+			message = fmt.Sprintf("waiting for cert-manager to issue the certificate into secret %s", name)
+		}
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionCertificateReady, metav1.ConditionFalse, ReasonCertificateNotReady, message)
+		return name, nil
+	}
+
+	if len(secret.Data[corev1.TLSCertKey]) == 0 || len(secret.Data[corev1.TLSPrivateKeyKey]) == 0 {
+		setCondition(status, webgame.GetGeneration(), webgamev2.ConditionCertificateReady, metav1.ConditionFalse, ReasonCertificateNotReady,
+			fmt.Sprintf("secret %s has no %s and %s", name, corev1.TLSCertKey, corev1.TLSPrivateKeyKey))
+		return name, nil
+	}
+
+	setCondition(status, webgame.GetGeneration(), webgamev2.ConditionCertificateReady, metav1.ConditionTrue, ReasonSynced, fmt.Sprintf("certificate found in secret %s", name))
+	return name, nil
+}
+
+// copyWildcardSecret keeps a copy of the wildcard certificate in namespace. It returns false, leaving the
+// secret untouched, when a secret with the name of the wildcard secret exists and is not a copy.
+func (r *WebGameReconciler) copyWildcardSecret(ctx context.Context, ref *corev1.SecretReference, namespace string) (bool, error) {
+	var source corev1.Secret
+	if err := r.getSecret(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, &source); err != nil {
+		if errors.IsNotFound(err) {
+			// reported by the CertificateReady condition as a missing copy
+			return true, nil
+		}
+		return false, err
+	}
+
+	// the copies made before they were labelled hold the wildcard certificate, they are adopted
+	var secret corev1.Secret
+	err := r.getSecret(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, &secret)
+	if client.IgnoreNotFound(err) != nil {
+		return false, err
+	} else if err == nil && secret.GetLabels()[wildcardCopyLabel] != "true" &&
+		(secret.Type != source.Type || !equality.Semantic.DeepEqual(secret.Data, source.Data)) {
+		return false, nil
+	}
+
+	// the secrets are not cached, so the copy is not written with ctrl.CreateOrUpdate
+	found := err == nil
+	existing := secret.DeepCopy()
+	secret.SetNamespace(namespace)
+	secret.SetName(ref.Name)
+	secret.SetLabels(labels.Merge(source.GetLabels(), map[string]string{wildcardCopyLabel: "true"}))
+	secret.Type = source.Type
+	secret.Data = source.Data
+	res := controllerutil.OperationResultNone
+	switch {
+	case !found:
+		if err := r.Create(ctx, &secret); err != nil {
+			return false, err
+		}
+		res = controllerutil.OperationResultCreated
+	case !equality.Semantic.DeepEqual(existing, &secret):
+		if err := r.Update(ctx, &secret); err != nil {
+			return false, err
+		}
+		res = controllerutil.OperationResultUpdated
+	}
+	if res != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("wildcard certificate copied", "secret", ref.Name, "res", res)
+	}
+	return true, nil
+}
+
+// releaseWildcardCopy deletes the copy of the wildcard certificate in the namespace of the webgame
+// once no other game of the namespace uses the wildcard mode.
+func (r *WebGameReconciler) releaseWildcardCopy(ctx context.Context, webgame *webgamev2.WebGame) error {
+	wildcard := r.Config.TLS.WildcardSecret
+	if wildcard == nil || wildcard.Namespace == "" || wildcard.Namespace == webgame.GetNamespace() {
+		return nil
+	}
+
+	var webgames webgamev2.WebGameList
+	if err := r.List(ctx, &webgames, client.InNamespace(webgame.GetNamespace()), client.MatchingFields{tlsSecretIndex: wildcard.Name}); err != nil {
+		return err
+	}
+	for i := range webgames.Items {
+		other := &webgames.Items[i]
+		tls := other.Spec.Routing.TLS
+		if other.GetUID() != webgame.GetUID() && other.GetDeletionTimestamp().IsZero() && tls != nil && tls.Mode == webgamev2.TLSModeWildcard {
+			return nil
+		}
+	}
+
+	secret := secretMetadata()
+	if err := r.Get(ctx, client.ObjectKey{Namespace: webgame.GetNamespace(), Name: wildcard.Name}, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if secret.GetLabels()[wildcardCopyLabel] != "true" {
+		return nil
+	}
+	if err := r.Delete(ctx, secret, client.Preconditions{UID: &secret.UID}); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("unused wildcard certificate copy deleted", "secret", wildcard.Name)
+	return nil
+}
+
+// certManagerAnnotations returns the ingress annotations requesting the certificate from cert-manager.
+func certManagerAnnotations(tls *webgamev2.TLSSpec) map[string]string {
+	annotations := map[string]string{}
+	if tls == nil || tls.Mode != webgamev2.TLSModeCertManager || tls.Issuer == nil {
+		return annotations
+	}
+	if tls.Issuer.Kind == "ClusterIssuer" {
+		annotations[certManagerClusterIssuerAnnotation] = tls.Issuer.Name
+	} else {
+		annotations[certManagerIssuerAnnotation] = tls.Issuer.Name
+	}
+	return annotations
+}
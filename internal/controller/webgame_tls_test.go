@@ -0,0 +1,84 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	webgamev2 "github.com/webgamedevelop/webgame/api/v2"
+	"github.com/webgamedevelop/webgame/internal/config"
+)
+
+var _ = Describe("Test wildcard certificate copies", func() {
+	var (
+		r       *WebGameReconciler
+		webgame *webgamev2.WebGame
+		copyKey = client.ObjectKey{Namespace: "games", Name: "wildcard-tls"}
+	)
+
+	BeforeEach(func() {
+		source := &corev1.Secret{Type: corev1.SecretTypeTLS, Data: map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")}}
+		source.SetNamespace("webgame-system")
+		source.SetName("wildcard-tls")
+
+		webgame = &webgamev2.WebGame{}
+		webgame.SetNamespace("games")
+		webgame.SetName("webgame-wildcard")
+		webgame.SetUID("webgame-wildcard-uid")
+		webgame.Spec.Routing.TLS = &webgamev2.TLSSpec{Mode: webgamev2.TLSModeWildcard}
+
+		cfg := config.Default()
+		cfg.TLS.WildcardSecret = &corev1.SecretReference{Namespace: source.GetNamespace(), Name: source.GetName()}
+		r = &WebGameReconciler{Scheme: scheme.Scheme, Config: cfg}
+		r.Client = fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(source, webgame).
+			WithIndex(&webgamev2.WebGame{}, tlsSecretIndex, r.indexTLSSecret).
+			Build()
+		r.APIReader = r.Client
+	})
+
+	It("leave a secret which is not a copy untouched", func() {
+		secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("user")}}
+		secret.SetNamespace(copyKey.Namespace)
+		secret.SetName(copyKey.Name)
+		Expect(r.Create(ctx, secret)).Should(Succeed())
+
+		copied, err := r.copyWildcardSecret(ctx, r.Config.TLS.WildcardSecret, copyKey.Namespace)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(copied).Should(BeFalse())
+		Expect(r.Get(ctx, copyKey, secret)).Should(Succeed())
+		Expect(secret.Data).Should(HaveKey("password"))
+
+		// the secret of the user is not deleted with the copies
+		webgame.Spec.Routing.TLS = nil
+		Expect(r.releaseWildcardCopy(ctx, webgame)).Should(Succeed())
+		Expect(r.Get(ctx, copyKey, secret)).Should(Succeed())
+	})
+
+	It("delete the copy once no game of the namespace uses it", func() {
+		copied, err := r.copyWildcardSecret(ctx, r.Config.TLS.WildcardSecret, copyKey.Namespace)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(copied).Should(BeTrue())
+		var secret corev1.Secret
+		Expect(r.Get(ctx, copyKey, &secret)).Should(Succeed())
+		Expect(secret.GetLabels()).Should(HaveKeyWithValue(wildcardCopyLabel, "true"))
+
+		// another game still uses the wildcard mode
+		other := webgame.DeepCopy()
+		other.SetName("webgame-wildcard-other")
+		other.SetUID("webgame-wildcard-other-uid")
+		other.SetResourceVersion("")
+		Expect(r.Create(ctx, other)).Should(Succeed())
+		Expect(r.releaseWildcardCopy(ctx, webgame)).Should(Succeed())
+		Expect(r.Get(ctx, copyKey, &secret)).Should(Succeed())
+
+		Expect(r.Delete(ctx, other)).Should(Succeed())
+		Expect(r.releaseWildcardCopy(ctx, webgame)).Should(Succeed())
+		Expect(apierrors.IsNotFound(r.Get(ctx, copyKey, &secret))).Should(BeTrue())
+	})
+})
@@ -0,0 +1,116 @@
+// Package ingress translates the route of a game into the configuration understood by
+// a specific ingress controller, since path rewriting is not part of the Ingress API.
+package ingress
+
+import (
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Names of the built-in dialects.
+const (
+	Nginx   = "nginx"
+	Traefik = "traefik"
+	HAProxy = "haproxy"
+)
+
+// Route is the game route an ingress is configured for.
+type Route struct {
+	// Namespace and Name of the ingress, also used for the extra objects of a dialect.
+	Namespace string
+	Name      string
+	// Prefix is the path prefix of the game, stripped before requests reach the game.
+	// The game is served at the root of the host without rewrite when it is empty.
+	Prefix string
+	// KeepPrefix matches Prefix without stripping it, for backends which strip it themselves.
+	KeepPrefix bool
+	// TLS is true when the ingress terminates TLS.
+	TLS bool
+	// RedirectHTTP redirects plain HTTP requests to HTTPS, only used with TLS.
+	RedirectHTTP bool
+	// HSTS sends the Strict-Transport-Security header when set, only used with TLS.
+	HSTS *HSTS
+}
+
+// HSTS is the Strict-Transport-Security policy of a route.
+type HSTS struct {
+	MaxAge            int64
+	IncludeSubDomains bool
+	Preload           bool
+}
+
+// Value returns the Strict-Transport-Security header value.
+func (h *HSTS) Value() string {
+	value := []string{fmt.Sprintf("max-age=%d", h.MaxAge)}
+	if h.IncludeSubDomains {
+		value = append(value, "includeSubDomains")
+	}
+	if h.Preload {
+		value = append(value, "preload")
+	}
+	return strings.Join(value, "; ")
+}
+
+// Config is the dialect specific part of an ingress.
+type Config struct {
+	Path        string
+	PathType    networkingv1.PathType
+	Annotations map[string]string
+	// Objects are extra resources the ingress depends on, like Traefik middlewares.
+	Objects []*unstructured.Unstructured
+	// Unused are the extra resources the dialect may create for the route but the route does not need.
+	Unused []*unstructured.Unstructured
+	// Unsupported are the features of the route the dialect cannot configure on the ingress, like HSTS.
+	Unsupported []string
+}
+
+// Dialect configures ingresses for one ingress controller.
+type Dialect interface {
+	// Name of the dialect.
+	Name() string
+	// Configure returns the ingress configuration of route.
+	Configure(route Route) Config
+}
+
+// Canary is implemented by the dialects able to split the traffic of a route between two ingresses.
+type Canary interface {
+	// CanaryAnnotations returns the annotations of the second ingress of the route,
+	// receiving weight percent of its requests.
+	CanaryAnnotations(weight int32) map[string]string
+}
+
+var dialects = map[string]Dialect{
+	Nginx:   nginx{},
+	Traefik: traefik{},
+	HAProxy: haproxy{},
+}
+
+// controllers maps the spec.controller of well-known ingress classes to their dialect.
+var controllers = map[string]string{
+	"k8s.io/ingress-nginx":                 Nginx,
+	"traefik.io/ingress-controller":        Traefik,
+	"haproxy.org/ingress-controller":       HAProxy,
+	"haproxy-ingress.github.io/controller": HAProxy,
+}
+
+// Lookup returns the dialect with the given name.
+func Lookup(name string) (Dialect, error) {
+	dialect, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ingress dialect %q", name)
+	}
+	return dialect, nil
+}
+
+// ForController returns the dialect of an ingress class controller, false when it is not known.
+func ForController(controller string) (Dialect, bool) {
+	for prefix, name := range controllers {
+		if controller == prefix || strings.HasPrefix(controller, prefix+"/") {
+			return dialects[name], true
+		}
+	}
+	return nil, false
+}
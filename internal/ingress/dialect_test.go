@@ -0,0 +1,177 @@
+package ingress
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestConfigure(t *testing.T) {
+	route := Route{Namespace: "webgames", Name: "webgame-2048", Prefix: "/2048"}
+	tls := route
+	tls.TLS, tls.RedirectHTTP = true, true
+	tls.HSTS = &HSTS{MaxAge: 600, IncludeSubDomains: true}
+
+	tests := []struct {
+		name        string
+		dialect     string
+		route       Route
+		path        string
+		pathType    networkingv1.PathType
+		annotations map[string]string
+		objects     []string
+		unsupported []string
+	}{{
+		name:        "nginx rewrite",
+		dialect:     Nginx,
+		route:       route,
+		path:        "/2048(/|$)(.*)",
+		pathType:    networkingv1.PathTypeImplementationSpecific,
+		annotations: map[string]string{nginxRewriteTarget: "/$2", nginxUseRegex: "true"},
+	}, {
+		name:        "nginx root",
+		dialect:     Nginx,
+		route:       Route{Namespace: "webgames", Name: "webgame-2048"},
+		path:        "/",
+		pathType:    networkingv1.PathTypePrefix,
+		annotations: map[string]string{},
+	}, {
+		name:        "nginx kept prefix",
+		dialect:     Nginx,
+		route:       Route{Namespace: "webgames", Name: "webgame-2048", Prefix: "/2048", KeepPrefix: true},
+		path:        "/2048",
+		pathType:    networkingv1.PathTypePrefix,
+		annotations: map[string]string{},
+	}, {
+		name:     "nginx tls",
+		dialect:  Nginx,
+		route:    tls,
+		path:     "/2048(/|$)(.*)",
+		pathType: networkingv1.PathTypeImplementationSpecific,
+		annotations: map[string]string{
+			nginxRewriteTarget: "/$2", nginxUseRegex: "true", nginxSSLRedirect: "true", nginxForceSSLRedirect: "true",
+		},
+		unsupported: []string{"HSTS"},
+	}, {
+		name:        "traefik rewrite",
+		dialect:     Traefik,
+		route:       route,
+		path:        "/2048",
+		pathType:    networkingv1.PathTypePrefix,
+		annotations: map[string]string{traefikRouterMiddlewares: "webgames-webgame-2048-strip-prefix@kubernetescrd"},
+		objects:     []string{"webgame-2048-strip-prefix"},
+	}, {
+		name:     "traefik tls",
+		dialect:  Traefik,
+		route:    tls,
+		path:     "/2048",
+		pathType: networkingv1.PathTypePrefix,
+		annotations: map[string]string{traefikRouterMiddlewares: "webgames-webgame-2048-strip-prefix@kubernetescrd," +
+			"webgames-webgame-2048-redirect-https@kubernetescrd,webgames-webgame-2048-hsts@kubernetescrd"},
+		objects: []string{"webgame-2048-strip-prefix", "webgame-2048-redirect-https", "webgame-2048-hsts"},
+	}, {
+		name:        "haproxy rewrite",
+		dialect:     HAProxy,
+		route:       route,
+		path:        "/2048",
+		pathType:    networkingv1.PathTypePrefix,
+		annotations: map[string]string{haproxyPathRewrite: `/2048/?(.*) /\1`},
+	}, {
+		name:     "haproxy tls",
+		dialect:  HAProxy,
+		route:    tls,
+		path:     "/2048",
+		pathType: networkingv1.PathTypePrefix,
+		annotations: map[string]string{
+			haproxyPathRewrite:       `/2048/?(.*) /\1`,
+			haproxySSLRedirect:       "true",
+			haproxyResponseSetHeader: `Strict-Transport-Security "max-age=600; includeSubDomains"`,
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialect, err := Lookup(tt.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := dialect.Configure(tt.route)
+			if config.Path != tt.path || config.PathType != tt.pathType {
+				t.Errorf("path = %s %s, want %s %s", config.PathType, config.Path, tt.pathType, tt.path)
+			}
+			if !reflect.DeepEqual(config.Annotations, tt.annotations) {
+				t.Errorf("annotations = %v, want %v", config.Annotations, tt.annotations)
+			}
+			var objects []string
+			for _, object := range config.Objects {
+				objects = append(objects, object.GetName())
+			}
+			if !reflect.DeepEqual(objects, tt.objects) {
+				t.Errorf("objects = %v, want %v", objects, tt.objects)
+			}
+			if !reflect.DeepEqual(config.Unsupported, tt.unsupported) {
+				t.Errorf("unsupported = %v, want %v", config.Unsupported, tt.unsupported)
+			}
+		})
+	}
+}
+
+func TestCanaryAnnotations(t *testing.T) {
+	tests := []struct {
+		dialect     string
+		annotations map[string]string
+	}{{
+		dialect:     Nginx,
+		annotations: map[string]string{nginxCanary: "true", nginxCanaryWeight: "20"},
+	}, {
+		dialect: Traefik,
+	}, {
+		dialect: HAProxy,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			dialect, err := Lookup(tt.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			canary, ok := dialect.(Canary)
+			if ok != (tt.annotations != nil) {
+				t.Fatalf("canary supported = %v, want %v", ok, tt.annotations != nil)
+			}
+			if !ok {
+				return
+			}
+			if annotations := canary.CanaryAnnotations(20); !reflect.DeepEqual(annotations, tt.annotations) {
+				t.Errorf("annotations = %v, want %v", annotations, tt.annotations)
+			}
+		})
+	}
+}
+
+func TestForController(t *testing.T) {
+	tests := []struct {
+		controller string
+		dialect    string
+	}{
+		{controller: "k8s.io/ingress-nginx", dialect: Nginx},
+		{controller: "traefik.io/ingress-controller", dialect: Traefik},
+		{controller: "haproxy.org/ingress-controller/haproxy", dialect: HAProxy},
+		{controller: "haproxy-ingress.github.io/controller", dialect: HAProxy},
+		{controller: "k8s.io/ingress-nginx-other"},
+		{controller: "example.com/ingress"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.controller, func(t *testing.T) {
+			dialect, ok := ForController(tt.controller)
+			if !ok {
+				if tt.dialect != "" {
+					t.Errorf("no dialect, want %s", tt.dialect)
+				}
+				return
+			}
+			if dialect.Name() != tt.dialect {
+				t.Errorf("dialect = %s, want %s", dialect.Name(), tt.dialect)
+			}
+		})
+	}
+}
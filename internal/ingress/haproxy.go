@@ -0,0 +1,42 @@
+package ingress
+
+import (
+	"fmt"
+	"strconv"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+const (
+	haproxyPathRewrite       = "haproxy.org/path-rewrite"
+	haproxySSLRedirect       = "haproxy.org/ssl-redirect"
+	haproxyResponseSetHeader = "haproxy.org/response-set-header"
+)
+
+// haproxy rewrites with the path-rewrite annotation of the HAProxy kubernetes ingress controller.
+type haproxy struct{}
+
+func (haproxy) Name() string {
+	return HAProxy
+}
+
+func (haproxy) Configure(route Route) Config {
+	config := Config{
+		Path:        "/",
+		PathType:    networkingv1.PathTypePrefix,
+		Annotations: map[string]string{},
+	}
+	if route.Prefix != "" {
+		config.Path = route.Prefix
+	}
+	if route.Prefix != "" && !route.KeepPrefix {
+		config.Annotations[haproxyPathRewrite] = fmt.Sprintf(`%s/?(.*) /\1`, route.Prefix)
+	}
+	if route.TLS {
+		config.Annotations[haproxySSLRedirect] = strconv.FormatBool(route.RedirectHTTP)
+		if route.HSTS != nil {
+			config.Annotations[haproxyResponseSetHeader] = fmt.Sprintf(`Strict-Transport-Security "%s"`, route.HSTS.Value())
+		}
+	}
+	return config
+}
@@ -0,0 +1,56 @@
+package ingress
+
+import (
+	"strconv"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+const (
+	nginxRewriteTarget    = "nginx.ingress.kubernetes.io/rewrite-target"
+	nginxUseRegex         = "nginx.ingress.kubernetes.io/use-regex"
+	nginxSSLRedirect      = "nginx.ingress.kubernetes.io/ssl-redirect"
+	nginxForceSSLRedirect = "nginx.ingress.kubernetes.io/force-ssl-redirect"
+	nginxCanary           = "nginx.ingress.kubernetes.io/canary"
+	nginxCanaryWeight     = "nginx.ingress.kubernetes.io/canary-weight"
+)
+
+// nginx rewrites with rewrite-target and a regex path, so no snippet is needed for the route.
+type nginx struct{}
+
+func (nginx) Name() string {
+	return Nginx
+}
+
+func (nginx) Configure(route Route) Config {
+	config := Config{
+		Path:        "/",
+		PathType:    networkingv1.PathTypePrefix,
+		Annotations: map[string]string{},
+	}
+	if route.Prefix != "" {
+		config.Path = route.Prefix
+	}
+	if route.Prefix != "" && !route.KeepPrefix {
+		config.Path = route.Prefix + "(/|$)(.*)"
+		config.PathType = networkingv1.PathTypeImplementationSpecific
+		config.Annotations[nginxRewriteTarget] = "/$2"
+		config.Annotations[nginxUseRegex] = "true"
+	}
+	if route.TLS {
+		config.Annotations[nginxSSLRedirect] = strconv.FormatBool(route.RedirectHTTP)
+		config.Annotations[nginxForceSSLRedirect] = strconv.FormatBool(route.RedirectHTTP)
+		if route.HSTS != nil {
+			// ingress-nginx only sets HSTS in its ConfigMap, for every ingress
+			config.Unsupported = append(config.Unsupported, "HSTS")
+		}
+	}
+	return config
+}
+
+func (nginx) CanaryAnnotations(weight int32) map[string]string {
+	return map[string]string{
+		nginxCanary:       "true",
+		nginxCanaryWeight: strconv.Itoa(int(weight)),
+	}
+}
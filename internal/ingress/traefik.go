@@ -0,0 +1,80 @@
+package ingress
+
+import (
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const traefikRouterMiddlewares = "traefik.ingress.kubernetes.io/router.middlewares"
+
+// MiddlewareGVK is the Traefik Middleware, handled as unstructured like the Gateway API resources.
+var MiddlewareGVK = schema.GroupVersionKind{Group: "traefik.io", Version: "v1alpha1", Kind: "Middleware"}
+
+// traefik strips the prefix with a StripPrefix Middleware, and redirects and sets HSTS with middlewares too.
+type traefik struct{}
+
+func (traefik) Name() string {
+	return Traefik
+}
+
+func (traefik) Configure(route Route) Config {
+	config := Config{
+		Path:        "/",
+		PathType:    networkingv1.PathTypePrefix,
+		Annotations: map[string]string{},
+	}
+	if route.Prefix != "" {
+		config.Path = route.Prefix
+	}
+
+	middlewares := []struct {
+		suffix string
+		used   bool
+		spec   func() map[string]interface{}
+	}{{
+		suffix: "strip-prefix",
+		used:   route.Prefix != "" && !route.KeepPrefix,
+		spec: func() map[string]interface{} {
+			return map[string]interface{}{"stripPrefix": map[string]interface{}{"prefixes": []interface{}{route.Prefix}}}
+		},
+	}, {
+		suffix: "redirect-https",
+		used:   route.TLS && route.RedirectHTTP,
+		spec: func() map[string]interface{} {
+			return map[string]interface{}{"redirectScheme": map[string]interface{}{"scheme": "https", "permanent": true}}
+		},
+	}, {
+		suffix: "hsts",
+		used:   route.TLS && route.HSTS != nil,
+		spec: func() map[string]interface{} {
+			return map[string]interface{}{"headers": map[string]interface{}{
+				"stsSeconds":           route.HSTS.MaxAge,
+				"stsIncludeSubdomains": route.HSTS.IncludeSubDomains,
+				"stsPreload":           route.HSTS.Preload,
+			}}
+		},
+	}}
+
+	var refs []string
+	for _, m := range middlewares {
+		middleware := &unstructured.Unstructured{}
+		middleware.SetGroupVersionKind(MiddlewareGVK)
+		middleware.SetNamespace(route.Namespace)
+		middleware.SetName(fmt.Sprintf("%s-%s", route.Name, m.suffix))
+		if !m.used {
+			config.Unused = append(config.Unused, middleware)
+			continue
+		}
+		middleware.Object["spec"] = m.spec()
+		config.Objects = append(config.Objects, middleware)
+		refs = append(refs, fmt.Sprintf("%s-%s@kubernetescrd", middleware.GetNamespace(), middleware.GetName()))
+	}
+	if len(refs) != 0 {
+		config.Annotations[traefikRouterMiddlewares] = strings.Join(refs, ",")
+	}
+	return config
+}
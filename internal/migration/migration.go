@@ -0,0 +1,105 @@
+// Package migration rewrites stored custom resources in the current storage version,
+// so older versions can be dropped from the CRD.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// retryInterval is how long the migrator waits before retrying a failed migration.
+const retryInterval = time.Minute
+
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=get;update;patch
+
+// StorageVersionMigrator rewrites every object of a CRD once, so the API server stores it in the
+// storage version, then drops the older versions from the CRD status.storedVersions.
+type StorageVersionMigrator struct {
+	// Client writes the objects and the CRD status.
+	Client client.Client
+	// Reader lists the objects, it should read from the API server rather than the cache.
+	Reader client.Reader
+	// CRDName is the name of the migrated CustomResourceDefinition.
+	CRDName string
+	// GVK is the list kind of the migrated resource.
+	GVK schema.GroupVersionKind
+}
+
+// Start implements manager.Runnable.
+func (m *StorageVersionMigrator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("migration").WithValues("crd", m.CRDName)
+	for {
+		err := m.migrate(ctx)
+		if err == nil {
+			return nil
+		}
+		logger.Error(err, "unable to migrate stored objects, retrying", "after", retryInterval)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader migrates.
+func (m *StorageVersionMigrator) NeedLeaderElection() bool {
+	return true
+}
+
+func (m *StorageVersionMigrator) migrate(ctx context.Context) error {
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := m.Reader.Get(ctx, client.ObjectKey{Name: m.CRDName}, &crd); err != nil {
+		return err
+	}
+
+	storageVersion := ""
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			storageVersion = version.Name
+		}
+	}
+	if storageVersion == "" {
+		return fmt.Errorf("no storage version in %s", m.CRDName)
+	}
+	if len(crd.Status.StoredVersions) == 1 && crd.Status.StoredVersions[0] == storageVersion {
+		return nil
+	}
+
+	var list metav1.PartialObjectMetadataList
+	list.SetGroupVersionKind(m.GVK)
+	for {
+		if err := m.Reader.List(ctx, &list, client.Continue(list.Continue)); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			// an empty patch makes the API server write the object in the storage version
+			err := m.Client.Patch(ctx, &list.Items[i], client.RawPatch(types.MergePatchType, []byte("{}")))
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		if list.Continue == "" {
+			break
+		}
+	}
+
+	patch := client.MergeFrom(crd.DeepCopy())
+	crd.Status.StoredVersions = []string{storageVersion}
+	if err := m.Client.Status().Patch(ctx, &crd, patch); err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("migrated stored objects", "crd", m.CRDName, "storageVersion", storageVersion)
+	return nil
+}